@@ -1,18 +1,36 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"rest-service/internal/auth"
+	"rest-service/internal/config"
+	"rest-service/internal/exchange"
 	"rest-service/internal/handlers"
+	"rest-service/internal/health"
+	"rest-service/internal/logging"
+	"rest-service/internal/metrics"
+	"rest-service/internal/ratelimit"
 	"rest-service/internal/repository"
+	"rest-service/internal/retry"
+	"rest-service/internal/server"
+	"rest-service/internal/tracing"
+	"rest-service/migrations"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
-	"github.com/pressly/goose/v3"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	_ "modernc.org/sqlite"
 
 	docs "rest-service/docs"
 
@@ -21,59 +39,225 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func LoggerMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		log.WithFields(log.Fields{
-			"method": c.Request.Method,
-			"path":   c.Request.URL.Path,
-			"client": c.ClientIP(),
-		}).Info("Incoming request")
+// openDB подключается к БД указанного драйвера и применяет миграции.
+// Пока БД недоступна, подключение повторяется с растущей задержкой, но не дольше ConnectTimeout
+func openDB(ctx context.Context, driverName, dsn string, cfg config.DatabaseConfig) *sql.DB {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		log.Fatal("Failed to connect to DB:", err)
+	}
+
+	connectCtx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+	defer cancel()
+	err = retry.Do(connectCtx, retry.DefaultBackoff, db.PingContext, func(attempt int, delay time.Duration, err error) {
+		log.WithError(err).WithFields(log.Fields{"attempt": attempt, "retry_in": delay.String()}).Warn("DB is not available yet")
+	})
+	if err != nil {
+		log.Fatal("Cannot ping DB:", err)
+	}
+
+	if err := migrations.Up(ctx, db, driverName, cfg.MigrationsDir); err != nil {
+		log.Fatal("Failed to run migrations:", err)
+	}
+	return db
+}
+
+// newAuthenticator собирает проверку JWT из настроек: общий секрет HS256 и/или ключи JWKS
+func newAuthenticator(cfg config.AuthConfig) (*auth.Authenticator, error) {
+	opts := auth.Options{Issuer: cfg.Issuer, Audience: cfg.Audience}
+	if cfg.HMACSecret != "" {
+		opts.HMACSecret = []byte(cfg.HMACSecret)
+	}
+	switch {
+	case cfg.JWKSFile != "":
+		keys, err := auth.LoadJWKSFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		opts.Keys = keys
+	case cfg.JWKSURL != "":
+		opts.Keys = auth.NewRemoteKeySet(cfg.JWKSURL, 10*time.Minute)
+	}
+	return auth.NewAuthenticator(opts)
+}
 
-		c.Next()
+// purgeIdempotencyKeys периодически удаляет истёкшие ключи идемпотентности, пока не отменён ctx
+func purgeIdempotencyKeys(ctx context.Context, repo repository.IdempotencyRepository, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := repo.DeleteExpired(ctx, time.Now())
+			if err != nil {
+				log.WithError(err).Warn("Failed to delete expired idempotency keys")
+				continue
+			}
+			log.WithField("deleted", deleted).Debug("Expired idempotency keys deleted")
+		}
+	}
+}
 
-		status := c.Writer.Status()
-		log.WithFields(log.Fields{
-			"status": status,
-		}).Info("Request completed")
+// serveMetrics отдаёт /metrics на отдельном адресе addr, который, в отличие от порта API, можно закрыть от внешнего трафика
+func serveMetrics(addr string, handler http.Handler, cfg config.ServerConfig) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
 	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", handler)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: cfg.ReadHeaderTimeout, WriteTimeout: cfg.WriteTimeout}
+	go func() {
+		log.Infof("Metrics are served on %s/metrics", ln.Addr())
+		if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			log.WithError(err).Error("Metrics server stopped")
+		}
+	}()
+	return srv, nil
 }
 
+// loadExchangeRates загружает курсы валют из файла, формат определяется по расширению
+func loadExchangeRates(ctx context.Context, repo repository.ExchangeRateRepository, path string) error {
+	format, err := exchange.FormatFromPath(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	rates, err := exchange.Parse(f, format)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := repo.Upsert(ctx, rates); err != nil {
+		return err
+	}
+	log.WithField("count", len(rates)).WithField("file", path).Info("Exchange rates loaded")
+	return nil
+}
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT in the form "Bearer <token>". Required when authentication is enabled
+
+// @securityDefinitions.apikey APIKeyAuth
+// @in header
+// @name X-API-Key
+// @description API key for service-to-service calls, an alternative to the bearer token
 func main() {
 	log.SetFormatter(&log.JSONFormatter{})
 	log.SetOutput(os.Stdout)
-	log.SetLevel(log.InfoLevel)
 
 	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found or error loading it")
-		log.Infof("DATABASE_URL: %s", os.Getenv("DATABASE_URL"))
+		log.Info("No .env file found, using environment and flags only")
 	}
-	log.Infof("DATABASE_URL: %s", os.Getenv("DATABASE_URL"))
 
-	db, err := sql.Open("postgres", os.Getenv("DATABASE_URL"))
+	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
-		log.Fatal("Failed to connect to DB:", err)
+		log.Fatal(err)
+	}
+	if err := logging.Setup(cfg.Log); err != nil {
+		log.Fatal(err)
 	}
-	defer db.Close()
+	if !log.IsLevelEnabled(log.DebugLevel) {
+		// Отладочный вывод gin (список маршрутов и т.п.) нужен только при уровне debug
+		gin.SetMode(gin.ReleaseMode)
+	}
+	log.WithFields(cfg.LogFields()).Info("Configuration loaded")
 
-	if err := db.Ping(); err != nil {
-		log.Fatal("Cannot ping DB:", err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		log.Fatal("Failed to set up tracing:", err)
 	}
 
-	if err := goose.Up(db, "migrations"); err != nil {
-		log.Fatal("Failed to run migrations:", err)
+	var db *sql.DB
+	var repo repository.SubscriptionRepository
+	var keyRepo repository.APIKeyRepository
+	var idempotencyRepo repository.IdempotencyRepository
+	var rateRepo repository.ExchangeRateRepository
+	switch cfg.Database.Driver {
+	case "postgres":
+		db = openDB(ctx, "postgres", cfg.Database.URL, cfg.Database)
+		repo = repository.NewPostgresSubscriptionRepository(db)
+		keyRepo = repository.NewPostgresAPIKeyRepository(db)
+		idempotencyRepo = repository.NewPostgresIdempotencyRepository(db)
+		rateRepo = repository.NewPostgresExchangeRateRepository(db)
+	case "sqlite":
+		// WAL и ожидание блокировки позволяют читать параллельно с записью
+		dsn := "file:" + cfg.Database.SQLitePath + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+		db = openDB(ctx, "sqlite", dsn, cfg.Database)
+		repo = repository.NewSQLiteSubscriptionRepository(db)
+		keyRepo = repository.NewSQLiteAPIKeyRepository(db)
+		idempotencyRepo = repository.NewSQLiteIdempotencyRepository(db)
+		rateRepo = repository.NewSQLiteExchangeRateRepository(db)
+	case "memory":
+		log.Warn("Using in-memory storage, data will be lost on restart")
+		repo = repository.NewMemorySubscriptionRepository()
+		keyRepo = repository.NewMemoryAPIKeyRepository()
+		idempotencyRepo = repository.NewMemoryIdempotencyRepository()
+		rateRepo = repository.NewMemoryExchangeRateRepository()
+	}
+	go purgeIdempotencyKeys(ctx, idempotencyRepo, time.Hour)
+	if cfg.Exchange.RatesFile != "" {
+		if err := loadExchangeRates(ctx, rateRepo, cfg.Exchange.RatesFile); err != nil {
+			log.Fatal("Failed to load exchange rates:", err)
+		}
 	}
 
-	repo := repository.NewPostgresSubscriptionRepository(db)
-	handler := handlers.NewSubscriptionHandler(repo)
+	m := metrics.New()
+	if db != nil {
+		m.RegisterDB(db, cfg.Database.Driver)
+	}
+	repo = m.InstrumentRepository(repo)
+	m.RegisterBusiness(repo)
 
-	r := gin.Default()
-	r.Use(LoggerMiddleware())
+	checker := health.NewChecker(2 * time.Second)
+	if db != nil {
+		checker.Add("database", db.PingContext)
+		checker.Add("migrations", func(ctx context.Context) error {
+			return migrations.CheckVersion(ctx, db, cfg.Database.Driver, cfg.Database.MigrationsDir)
+		})
+	}
+
+	handler := handlers.NewSubscriptionHandler(repo, rateRepo)
+	rateHandler := handlers.NewExchangeRateHandler(rateRepo)
+	keyHandler := handlers.NewAPIKeyHandler(keyRepo)
+	healthHandler := handlers.NewHealthHandler(checker)
+
+	r := gin.New()
+	// Без доверенных прокси адрес клиента берётся из соединения, а X-Forwarded-For игнорируется
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatal("Invalid trusted proxies:", err)
+	}
+	r.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithFilter(func(req *http.Request) bool {
+		// Пробы вызываются постоянно и только засоряли бы трассы
+		switch req.URL.Path {
+		case "/healthz", "/readyz":
+			return false
+		}
+		return true
+	})))
+	r.Use(handlers.RequestID())
+	r.Use(m.Middleware())
+	r.Use(handlers.AccessLog())
+	r.Use(handlers.ErrorMiddleware())
+	r.Use(handlers.Recovery())
+	r.Use(handlers.MaxBodySize(cfg.Server.MaxBodyBytes))
+	r.NoRoute(handlers.NoRoute)
 
 	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowOrigins:     cfg.CORS.AllowOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", handlers.APIKeyHeader, handlers.IdempotencyKeyHeader, "If-Match", "If-None-Match", handlers.RequestIDHeader, "traceparent", "tracestate"},
+		ExposeHeaders:    []string{"Content-Length", "X-Total-Count", "Link", "ETag", handlers.RequestIDHeader, handlers.IdempotentReplayedHeader, "Retry-After", handlers.RateLimitLimitHeader, handlers.RateLimitRemainingHeader, handlers.RateLimitResetHeader},
 		AllowCredentials: false,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -84,17 +268,91 @@ func main() {
 		c.JSON(200, gin.H{"message": "Welcome to rest-service API"})
 	})
 
-	r.POST("/subscriptions", handler.Create)
-	r.GET("/subscriptions", handler.GetAll)
-	r.GET("/subscriptions/:id", handler.GetByID)
-	r.PUT("/subscriptions/:id", handler.Update)
-	r.DELETE("/subscriptions/:id", handler.Delete)
-	r.GET("/subscriptions/sum", handler.GetSum)
+	r.GET("/healthz", healthHandler.Liveness)
+	r.GET("/readyz", healthHandler.Readiness)
+
+	// Лимиты запросов действуют только на API: пробы не должны получать 429
+	limitStore := ratelimit.NewMemoryStore()
+	rateLimit := func(name string, budget config.RateLimitBudget, key handlers.RateKey) gin.HandlerFunc {
+		if !cfg.RateLimit.Enabled {
+			return func(c *gin.Context) { c.Next() }
+		}
+		return handlers.RateLimit(limitStore, name, ratelimit.Limit{Rate: budget.Rate, Burst: budget.Burst}, key)
+	}
+	expensive := func(route string) gin.HandlerFunc {
+		return rateLimit(route, cfg.RateLimit.Expensive, handlers.ByClient)
+	}
+
+	// Пробы и документация остаются открытыми, учётные данные нужны только для API.
+	// Лимит по IP проверяется до аутентификации, чтобы ограничить и перебор ключей
+	authenticate := []gin.HandlerFunc{rateLimit("ip", cfg.RateLimit.PerIP, handlers.ByIP)}
+	if cfg.Auth.Enabled || cfg.APIKeys.Enabled {
+		// JWT и API-ключи включаются независимо: сервисам с ключами не нужна настройка JWT
+		var tokens *auth.Authenticator
+		if cfg.Auth.Enabled {
+			if tokens, err = newAuthenticator(cfg.Auth); err != nil {
+				log.Fatal("Failed to set up authentication:", err)
+			}
+		}
+		var keys *auth.APIKeyAuthenticator
+		if cfg.AcceptsAPIKeys() {
+			keys = auth.NewAPIKeyAuthenticator(keyRepo, cfg.APIKeys.BootstrapKey)
+			if cfg.APIKeys.BootstrapKey != "" {
+				log.Warn("Bootstrap API key is configured, remove it once the first admin key is issued")
+			}
+		}
+		authenticate = append(authenticate, handlers.Authenticate(tokens, keys))
+	} else {
+		log.Warn("Authentication is disabled, the API is open to everyone except admin endpoints (/api-keys, POST /exchange-rates)")
+	}
+	authenticate = append(authenticate, rateLimit("client", cfg.RateLimit.PerClient, handlers.ByClient))
+
+	read := handlers.RequireScope(auth.ScopeSubscriptionsRead)
+	write := handlers.RequireScope(auth.ScopeSubscriptionsWrite)
+	api := r.Group("/subscriptions", authenticate...)
+	api.POST("", write, handlers.Idempotency(idempotencyRepo, cfg.Idempotency.TTL, cfg.Idempotency.LockTimeout), handler.Create)
+	api.GET("", read, expensive("list"), handler.GetAll)
+	api.GET("/:id", read, handler.GetByID)
+	api.PUT("/:id", write, handler.Update)
+	api.PATCH("/:id", write, handler.Patch)
+	api.DELETE("/:id", write, handler.Delete)
+	api.GET("/sum", handlers.RequireScope(auth.ScopeReportsRead), expensive("sum"), handler.GetSum)
+	api.GET("/timeline", handlers.RequireScope(auth.ScopeReportsRead), expensive("timeline"), handler.GetTimeline)
+	api.GET("/report", handlers.RequireScope(auth.ScopeReportsRead), expensive("report"), handler.GetReport)
+
+	keys := r.Group("/api-keys", authenticate...)
+	keys.Use(handlers.RequireScope(auth.ScopeAdmin))
+	keys.POST("", keyHandler.Create)
+	keys.GET("", keyHandler.List)
+	keys.POST("/:id/rotate", keyHandler.Rotate)
+	keys.DELETE("/:id", keyHandler.Revoke)
+
+	rates := r.Group("/exchange-rates", authenticate...)
+	rates.POST("", handlers.RequireScope(auth.ScopeAdmin), rateHandler.Import)
+	rates.GET("", handlers.RequireScope(auth.ScopeReportsRead), rateHandler.List)
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	log.Info("Starting server on :8080")
-	if err := r.Run(":8080"); err != nil {
-		log.Fatal("Failed to run server:", err)
+	srv := server.New(r, cfg.Server)
+	srv.BeforeShutdown(checker.SetShuttingDown)
+	// Хуки выполняются в обратном порядке: трассировка останавливается последней, после закрытия БД
+	srv.OnShutdown("tracing", shutdownTracing)
+	if db != nil {
+		// Соединения с БД закрываются только после того, как завершились все запросы
+		srv.OnShutdown("database", func(ctx context.Context) error { return db.Close() })
+	}
+
+	if cfg.Metrics.Addr != "" {
+		// Метрики, в том числе число подписок и траты по сервисам, не отдаются на публичном порту
+		metricsSrv, err := serveMetrics(cfg.Metrics.Addr, m.Handler(), cfg.Server)
+		if err != nil {
+			log.Fatal("Failed to serve metrics:", err)
+		}
+		// Регистрируется последним и останавливается первым, после завершения запросов API
+		srv.OnShutdown("metrics", metricsSrv.Shutdown)
+	}
+
+	if err := srv.Run(ctx); err != nil {
+		log.Fatal("Server stopped with error:", err)
 	}
 }
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"rest-service/internal/logging"
+	"rest-service/internal/models"
+	"rest-service/internal/repository"
+	"time"
+)
+
+// Права API-ключей
+const (
+	ScopeSubscriptionsRead  = "subscriptions:read"
+	ScopeSubscriptionsWrite = "subscriptions:write"
+	ScopeReportsRead        = "reports:read"
+	ScopeAdmin              = "admin"
+)
+
+// Scopes — все права, которые можно выдать API-ключу
+var Scopes = []string{ScopeSubscriptionsRead, ScopeSubscriptionsWrite, ScopeReportsRead, ScopeAdmin}
+
+const (
+	// apiKeyMarker — начало каждого ключа, чтобы его было легко узнать, например, сканерам секретов
+	apiKeyMarker = "rsk_"
+	// apiKeyPrefixLen — сколько первых символов ключа хранится открыто для опознания
+	apiKeyPrefixLen = len(apiKeyMarker) + 8
+	// lastUsedInterval — не чаще этого время последнего использования ключа записывается в БД
+	lastUsedInterval = time.Minute
+)
+
+// BootstrapAPIKeyID — APIKeyID ключа администратора из конфигурации, которого нет в хранилище
+const BootstrapAPIKeyID = -1
+
+// GenerateAPIKey создаёт случайный ключ. Сам ключ показывается клиенту один раз,
+// а хранятся только его начало prefix и хеш hash
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+	key = apiKeyMarker + base64.RawURLEncoding.EncodeToString(secret)
+	return key, key[:apiKeyPrefixLen], HashAPIKey(key), nil
+}
+
+// HashAPIKey возвращает SHA-256 ключа в hex. Ключ случайный и длинный, поэтому медленный хеш не нужен
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyStore — хранилище, по которому проверяются API-ключи
+type APIKeyStore interface {
+	GetByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	TouchLastUsed(ctx context.Context, id int, at time.Time) error
+}
+
+// APIKeyAuthenticator проверяет API-ключи и отмечает их использование
+type APIKeyAuthenticator struct {
+	store         APIKeyStore
+	bootstrapHash string
+	now           func() time.Time
+}
+
+// NewAPIKeyAuthenticator проверяет ключи из store. Непустой bootstrapKey — ключ администратора из конфигурации:
+// им выпускают первые ключи через /api-keys, пока в хранилище нет ни одного
+func NewAPIKeyAuthenticator(store APIKeyStore, bootstrapKey string) *APIKeyAuthenticator {
+	a := &APIKeyAuthenticator{store: store, now: time.Now}
+	if bootstrapKey != "" {
+		a.bootstrapHash = HashAPIKey(bootstrapKey)
+	}
+	return a
+}
+
+// Authenticate находит действующий ключ и возвращает сервис с правами этого ключа.
+// Ошибки хранилища возвращаются как есть, чтобы недоступная БД не выглядела как неверный ключ
+func (a *APIKeyAuthenticator) Authenticate(ctx context.Context, key string) (Principal, error) {
+	hash := HashAPIKey(key)
+	if a.bootstrapHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(a.bootstrapHash)) == 1 {
+		logging.FromContext(ctx).Warn("Bootstrap API key used, remove it from the configuration once real keys are issued")
+		return Principal{APIKeyID: BootstrapAPIKeyID, Scopes: []string{ScopeAdmin}}, nil
+	}
+	stored, err := a.store.GetByHash(ctx, hash)
+	if errors.Is(err, repository.ErrNotFound) {
+		return Principal{}, fmt.Errorf("%w: unknown api key", ErrUnauthenticated)
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+	now := a.now()
+	if !stored.Active(now) {
+		return Principal{}, fmt.Errorf("%w: api key %d is revoked or expired", ErrUnauthenticated, stored.ID)
+	}
+
+	if stored.LastUsedAt == nil || now.Sub(*stored.LastUsedAt) >= lastUsedInterval {
+		if err := a.store.TouchLastUsed(ctx, stored.ID, now); err != nil {
+			logging.FromContext(ctx).WithError(err).WithField("api_key_id", stored.ID).Warn("Failed to record API key usage")
+		}
+	}
+	return Principal{APIKeyID: stored.ID, Scopes: stored.Scopes}, nil
+}
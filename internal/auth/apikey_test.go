@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rest-service/internal/models"
+	"rest-service/internal/repository"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, hash, err := GenerateAPIKey()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, "rsk_"))
+	assert.True(t, strings.HasPrefix(key, prefix))
+	assert.Len(t, prefix, 12)
+	assert.Equal(t, HashAPIKey(key), hash)
+
+	other, _, _, err := GenerateAPIKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryAPIKeyRepository()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	a := NewAPIKeyAuthenticator(repo, "")
+	a.now = func() time.Time { return now }
+
+	issue := func(scopes []string, expires *time.Time) (string, int) {
+		key, prefix, hash, err := GenerateAPIKey()
+		require.NoError(t, err)
+		id, err := repo.Create(ctx, &models.APIKey{Name: "job", Prefix: prefix, Hash: hash, Scopes: scopes, ExpiresAt: expires})
+		require.NoError(t, err)
+		return key, id
+	}
+
+	key, id := issue([]string{ScopeReportsRead}, nil)
+	p, err := a.Authenticate(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, id, p.APIKeyID)
+	assert.True(t, p.IsService())
+	assert.True(t, p.HasScope(ScopeReportsRead))
+	assert.False(t, p.HasScope(ScopeSubscriptionsWrite))
+	assert.False(t, p.IsAdmin())
+
+	// Время использования пишется не чаще раза в минуту
+	stored, _ := repo.GetByID(ctx, id)
+	assert.Equal(t, now, *stored.LastUsedAt)
+	now = now.Add(30 * time.Second)
+	_, err = a.Authenticate(ctx, key)
+	require.NoError(t, err)
+	stored, _ = repo.GetByID(ctx, id)
+	assert.Equal(t, now.Add(-30*time.Second), *stored.LastUsedAt)
+
+	_, err = a.Authenticate(ctx, "rsk_unknown")
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	require.NoError(t, repo.Revoke(ctx, id, now))
+	_, err = a.Authenticate(ctx, key)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	expires := now.Add(time.Hour)
+	key, _ = issue([]string{ScopeAdmin}, &expires)
+	p, err = a.Authenticate(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, p.IsAdmin())
+	assert.True(t, p.HasScope(ScopeSubscriptionsWrite))
+	now = expires
+	_, err = a.Authenticate(ctx, key)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestAPIKeyAuthenticator_Bootstrap(t *testing.T) {
+	ctx := context.Background()
+	bootstrap := "rsk_bootstrap-0123456789abcdef0123456789"
+	a := NewAPIKeyAuthenticator(repository.NewMemoryAPIKeyRepository(), bootstrap)
+
+	p, err := a.Authenticate(ctx, bootstrap)
+	require.NoError(t, err)
+	assert.Equal(t, BootstrapAPIKeyID, p.APIKeyID)
+	assert.True(t, p.IsService())
+	assert.True(t, p.IsAdmin())
+
+	_, err = a.Authenticate(ctx, bootstrap+"x")
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+	_, err = NewAPIKeyAuthenticator(repository.NewMemoryAPIKeyRepository(), "").Authenticate(ctx, "")
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestPrincipal_HasScope(t *testing.T) {
+	user := Principal{Roles: []string{"user"}}
+	assert.True(t, user.HasScope(ScopeSubscriptionsWrite))
+	assert.False(t, user.HasScope(ScopeAdmin))
+	assert.True(t, Principal{Roles: []string{RoleAdmin}}.HasScope(ScopeAdmin))
+}
@@ -0,0 +1,151 @@
+// Package auth проверяет JWT (HS256 и RS256 с ключами из JWKS) и хранит в контексте
+// пользователя, от имени которого выполняется запрос
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// RoleAdmin — роль, снимающая ограничение доступа только к своим подпискам
+const RoleAdmin = "admin"
+
+// ErrUnauthenticated — токен отсутствует, подделан, просрочен или не подходит сервису
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Principal — аутентифицированный пользователь или сервис с API-ключом
+type Principal struct {
+	UserID   uuid.UUID
+	Roles    []string
+	APIKeyID int      // ID API-ключа; 0 — пользователь с JWT
+	Scopes   []string // права API-ключа
+}
+
+// IsAdmin сообщает, есть ли у пользователя роль admin или у API-ключа право admin
+func (p Principal) IsAdmin() bool {
+	return slices.Contains(p.Roles, RoleAdmin) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+// IsService сообщает, что запрос выполняется сервисом по API-ключу, а не пользователем
+func (p Principal) IsService() bool {
+	return p.APIKeyID != 0
+}
+
+// ID возвращает идентификатор пользователя или API-ключа, уникальный среди обоих видов
+func (p Principal) ID() string {
+	if p.IsService() {
+		return "key:" + strconv.Itoa(p.APIKeyID)
+	}
+	return "user:" + p.UserID.String()
+}
+
+// HasScope сообщает, разрешено ли действие scope. Администратору разрешено всё,
+// API-ключу — только перечисленные при выпуске права, а пользователю — всё, кроме admin
+func (p Principal) HasScope(scope string) bool {
+	switch {
+	case p.IsAdmin():
+		return true
+	case p.IsService():
+		return slices.Contains(p.Scopes, scope)
+	default:
+		return scope != ScopeAdmin
+	}
+}
+
+// Claims — поля JWT, которые использует сервис. Subject содержит UUID пользователя
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+}
+
+type principalKey struct{}
+
+// WithPrincipal возвращает контекст с пользователем запроса
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext возвращает пользователя запроса; ok == false, если запрос анонимный
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// KeySet возвращает открытый ключ RS256 по идентификатору kid из заголовка токена
+type KeySet interface {
+	Key(ctx context.Context, kid string) (interface{}, error)
+}
+
+// Authenticator проверяет подпись и стандартные поля JWT
+type Authenticator struct {
+	hmacSecret []byte
+	keys       KeySet
+	parser     *jwt.Parser
+}
+
+// Options — параметры проверки токенов. Должен быть задан хотя бы один источник ключей
+type Options struct {
+	HMACSecret []byte // секрет для HS256
+	Keys       KeySet // ключи для RS256
+	Issuer     string // ожидаемый iss; пусто — не проверяется
+	Audience   string // ожидаемый aud; пусто — не проверяется
+}
+
+// NewAuthenticator создаёт проверку токенов. Принимаются только алгоритмы настроенных источников ключей
+func NewAuthenticator(opts Options) (*Authenticator, error) {
+	if len(opts.HMACSecret) == 0 && opts.Keys == nil {
+		return nil, errors.New("auth: no HMAC secret or JWKS configured")
+	}
+
+	var methods []string
+	if len(opts.HMACSecret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if opts.Keys != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	parserOpts := []jwt.ParserOption{
+		// Алгоритм берётся из заголовка токена, поэтому разрешаем только настроенные
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+	return &Authenticator{hmacSecret: opts.HMACSecret, keys: opts.Keys, parser: jwt.NewParser(parserOpts...)}, nil
+}
+
+// Authenticate проверяет токен и возвращает пользователя из поля sub
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (Principal, error) {
+	var claims Claims
+	_, err := a.parser.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		switch t.Method.Alg() {
+		case jwt.SigningMethodHS256.Alg():
+			return a.hmacSecret, nil
+		case jwt.SigningMethodRS256.Alg():
+			kid, _ := t.Header["kid"].(string)
+			return a.keys.Key(ctx, kid)
+		}
+		return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+	})
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: subject must be a user UUID", ErrUnauthenticated)
+	}
+	return Principal{UserID: userID, Roles: claims.Roles}, nil
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var secret = []byte("test-secret")
+
+func claims(sub string, roles ...string) Claims {
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   sub,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Roles: roles,
+	}
+}
+
+func signHS256(t *testing.T, c Claims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(secret)
+	require.NoError(t, err)
+	return token
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, c Claims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func jwksJSON(t *testing.T, keys map[string]*rsa.PrivateKey) []byte {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	for kid, k := range keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA", Kid: kid, Use: "sig", Alg: "RS256",
+			N: base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		})
+	}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	return data
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func TestAuthenticator_HS256(t *testing.T) {
+	a, err := NewAuthenticator(Options{HMACSecret: secret, Issuer: "https://idp.example"})
+	require.NoError(t, err)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	c := claims(userID.String(), RoleAdmin)
+	c.Issuer = "https://idp.example"
+	p, err := a.Authenticate(ctx, signHS256(t, c))
+	require.NoError(t, err)
+	assert.Equal(t, userID, p.UserID)
+	assert.True(t, p.IsAdmin())
+
+	expired := claims(userID.String())
+	expired.Issuer = "https://idp.example"
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	wrongIssuer := claims(userID.String())
+	wrongIssuer.Issuer = "https://evil.example"
+	notUUID := claims("alice")
+	notUUID.Issuer = "https://idp.example"
+	noExpiry := claims(userID.String())
+	noExpiry.Issuer = "https://idp.example"
+	noExpiry.ExpiresAt = nil
+
+	for name, token := range map[string]string{
+		"expired":      signHS256(t, expired),
+		"wrong issuer": signHS256(t, wrongIssuer),
+		"not uuid":     signHS256(t, notUUID),
+		"no expiry":    signHS256(t, noExpiry),
+		"garbage":      "not-a-jwt",
+		// RS256 не настроен, значит такие токены не принимаются
+		"rs256": signRS256(t, newRSAKey(t), "k1", c),
+	} {
+		_, err := a.Authenticate(ctx, token)
+		assert.ErrorIs(t, err, ErrUnauthenticated, name)
+	}
+}
+
+func TestAuthenticator_JWKSFile(t *testing.T) {
+	key := newRSAKey(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwksJSON(t, map[string]*rsa.PrivateKey{"k1": key}), 0o600))
+
+	keys, err := LoadJWKSFile(path)
+	require.NoError(t, err)
+	a, err := NewAuthenticator(Options{Keys: keys})
+	require.NoError(t, err)
+
+	userID := uuid.New()
+	p, err := a.Authenticate(context.Background(), signRS256(t, key, "k1", claims(userID.String())))
+	require.NoError(t, err)
+	assert.Equal(t, userID, p.UserID)
+	assert.False(t, p.IsAdmin())
+
+	_, err = a.Authenticate(context.Background(), signRS256(t, newRSAKey(t), "k1", claims(userID.String())))
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+	// HS256 не настроен: токен, подписанный «открытым ключом» как секретом, не проходит
+	_, err = a.Authenticate(context.Background(), signHS256(t, claims(userID.String())))
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestRemoteKeySet_Rotation(t *testing.T) {
+	oldKey, newKey := newRSAKey(t), newRSAKey(t)
+	var current atomic.Value
+	current.Store(jwksJSON(t, map[string]*rsa.PrivateKey{"old": oldKey}))
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write(current.Load().([]byte))
+	}))
+	defer srv.Close()
+
+	keys := NewRemoteKeySet(srv.URL, time.Hour)
+	keys.minRefresh = 0
+	a, err := NewAuthenticator(Options{Keys: keys})
+	require.NoError(t, err)
+	ctx := context.Background()
+	sub := uuid.NewString()
+
+	_, err = a.Authenticate(ctx, signRS256(t, oldKey, "old", claims(sub)))
+	require.NoError(t, err)
+	_, err = a.Authenticate(ctx, signRS256(t, oldKey, "old", claims(sub)))
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), fetches.Load(), "keys must be cached")
+
+	// Провайдер сменил ключ: неизвестный kid приводит к досрочной перезагрузке набора
+	current.Store(jwksJSON(t, map[string]*rsa.PrivateKey{"new": newKey}))
+	_, err = a.Authenticate(ctx, signRS256(t, newKey, "new", claims(sub)))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func TestRemoteKeySet_SlowProvider(t *testing.T) {
+	key := newRSAKey(t)
+	body := jwksJSON(t, map[string]*rsa.PrivateKey{"k1": key})
+	var fetches atomic.Int32
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Первая загрузка проходит сразу, следующие ждут, пока тест их не отпустит
+		if fetches.Add(1) > 1 {
+			<-block
+		}
+		w.Write(body)
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	keys := NewRemoteKeySet(srv.URL, time.Hour)
+	keys.minRefresh = 0
+	ctx := context.Background()
+	_, err := keys.Key(ctx, "k1")
+	require.NoError(t, err)
+
+	// Неизвестные kid ждут одну общую загрузку, а запросы с известным kid не ждут её вовсе
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			waitCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+			defer cancel()
+			_, err := keys.Key(waitCtx, "rotated")
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		}()
+	}
+	require.Eventually(t, func() bool { return fetches.Load() == 2 }, time.Second, 5*time.Millisecond)
+
+	start := time.Now()
+	k, err := keys.Key(ctx, "k1")
+	require.NoError(t, err)
+	assert.Equal(t, &key.PublicKey, k)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	wg.Wait()
+	assert.Equal(t, int32(2), fetches.Load())
+}
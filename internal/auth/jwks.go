@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// jwk — ключ RSA в формате JSON Web Key (RFC 7517)
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// parseJWKS разбирает JSON Web Key Set и возвращает ключи RSA для подписи по kid.
+// Ключи других типов и назначений пропускаются
+func parseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != "RS256") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("parse JWKS key %q: invalid modulus", k.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("parse JWKS key %q: invalid exponent", k.Kid)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("parse JWKS: no RS256 signing keys")
+	}
+	return keys, nil
+}
+
+// lookupKey ищет ключ по kid. Токен без kid допускается, только если ключ один
+func lookupKey(keys map[string]*rsa.PublicKey, kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, k := range keys {
+			return k, true
+		}
+	}
+	k, ok := keys[kid]
+	return k, ok
+}
+
+// staticKeySet — ключи, прочитанные один раз при старте
+type staticKeySet map[string]*rsa.PublicKey
+
+// LoadJWKSFile читает JWKS из файла
+func LoadJWKSFile(path string) (KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read JWKS file: %w", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+	return staticKeySet(keys), nil
+}
+
+func (s staticKeySet) Key(ctx context.Context, kid string) (interface{}, error) {
+	if k, ok := lookupKey(s, kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// RemoteKeySet загружает JWKS по URL и кеширует ключи на ttl.
+// Если токен подписан неизвестным ключом (после ротации у провайдера), набор перечитывается
+// досрочно. Запросы к провайдеру выполняются не чаще раза в minRefresh, чтобы поддельные kid
+// и недоступность провайдера не превращались в лавину запросов.
+// Загрузка идёт вне блокировки и одна на всех: запросы с известным kid её не ждут
+type RemoteKeySet struct {
+	url        string
+	client     *http.Client
+	ttl        time.Duration
+	minRefresh time.Duration
+
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	refreshing  chan struct{} // закрывается по окончании текущей загрузки; nil — загрузки нет
+	refreshErr  error         // ошибка последней загрузки
+}
+
+func NewRemoteKeySet(url string, ttl time.Duration) *RemoteKeySet {
+	return &RemoteKeySet{
+		url:        url,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		ttl:        ttl,
+		minRefresh: time.Minute,
+	}
+}
+
+func (r *RemoteKeySet) Key(ctx context.Context, kid string) (interface{}, error) {
+	k, ok, fresh := r.lookup(kid)
+	if ok && fresh {
+		return k, nil
+	}
+
+	done := r.startRefresh()
+	if ok {
+		// Устаревший ключ ещё годится, пока набор перечитывается в фоне
+		return k, nil
+	}
+	if done == nil {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	// Если провайдер недоступен, продолжаем пользоваться последним успешно загруженным набором
+	if k, ok, _ := r.lookup(kid); ok {
+		return k, nil
+	}
+	r.mu.RLock()
+	err := r.refreshErr
+	r.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// lookup ищет ключ в текущем наборе и сообщает, не истёк ли ttl набора
+func (r *RemoteKeySet) lookup(kid string) (key *rsa.PublicKey, ok, fresh bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok = lookupKey(r.keys, kid)
+	return key, ok, time.Since(r.fetchedAt) < r.ttl
+}
+
+// startRefresh запускает загрузку набора, если она ещё не идёт и minRefresh прошёл.
+// Возвращает канал, который закроется по окончании загрузки, или nil, если загружать пока нельзя
+func (r *RemoteKeySet) startRefresh() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.refreshing != nil {
+		return r.refreshing
+	}
+	if time.Since(r.lastAttempt) < r.minRefresh {
+		return nil
+	}
+	r.lastAttempt = time.Now()
+	done := make(chan struct{})
+	r.refreshing = done
+
+	// Загрузка не привязана к запросу, который её начал: её результат ждут и другие запросы
+	go func() {
+		keys, err := r.fetch(context.Background())
+		r.mu.Lock()
+		if err == nil {
+			r.keys, r.fetchedAt = keys, time.Now()
+		}
+		r.refreshErr, r.refreshing = err, nil
+		r.mu.Unlock()
+		close(done)
+	}()
+	return done
+}
+
+func (r *RemoteKeySet) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch JWKS: unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	return parseJWKS(data)
+}
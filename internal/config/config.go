@@ -0,0 +1,519 @@
+// Package config загружает типизированную конфигурацию сервиса.
+// Источники в порядке возрастания приоритета: значения по умолчанию, YAML-файл,
+// переменные окружения (и их варианты с суффиксом _FILE для Docker secrets), флаги командной строки
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Config — конфигурация сервиса
+type Config struct {
+	Server      ServerConfig      `yaml:"server"`
+	Database    DatabaseConfig    `yaml:"database"`
+	CORS        CORSConfig        `yaml:"cors"`
+	Log         LogConfig         `yaml:"log"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Auth        AuthConfig        `yaml:"auth"`
+	APIKeys     APIKeysConfig     `yaml:"api_keys"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Exchange    ExchangeConfig    `yaml:"exchange"`
+	Metrics     MetricsConfig     `yaml:"metrics"`
+}
+
+type ServerConfig struct {
+	Port              int           `yaml:"port"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"` // сколько ждать завершения текущих запросов при остановке
+	// ShutdownDrainDelay — сколько после сигнала остановки продолжать принимать запросы с /readyz = 503,
+	// чтобы балансировщик и пробы успели вывести экземпляр из ротации
+	ShutdownDrainDelay time.Duration `yaml:"shutdown_drain_delay"`
+	MaxBodyBytes       int64         `yaml:"max_body_bytes"`  // максимальный размер тела запроса
+	TrustedProxies     []string      `yaml:"trusted_proxies"` // прокси, которым можно доверить X-Forwarded-For; пусто — никому
+}
+
+type DatabaseConfig struct {
+	Driver         string        `yaml:"driver"`          // postgres, sqlite или memory
+	URL            string        `yaml:"url"`             // строка подключения PostgreSQL, содержит пароль
+	SQLitePath     string        `yaml:"sqlite_path"`     // путь к файлу БД SQLite
+	MigrationsDir  string        `yaml:"migrations_dir"`  // каталог миграций; пусто — встроенные в бинарник
+	ConnectTimeout time.Duration `yaml:"connect_timeout"` // сколько ждать доступности БД при старте
+}
+
+type CORSConfig struct {
+	AllowOrigins []string `yaml:"allow_origins"`
+}
+
+// Форматы логов
+const (
+	LogFormatJSON   = "json"
+	LogFormatText   = "text"   // человекочитаемый, с цветами в терминале
+	LogFormatLogfmt = "logfmt" // key=value без цветов
+)
+
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"` // json, text или logfmt
+}
+
+// Экспортёры спанов OpenTelemetry
+const (
+	TracingExporterNone   = "none"
+	TracingExporterOTLP   = "otlp"   // OTLP/HTTP, например в OpenTelemetry Collector или Jaeger
+	TracingExporterStdout = "stdout" // JSON в стандартный вывод, для локальной отладки
+	TracingExporterFile   = "file"   // JSON в файл
+)
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`     // none, otlp, stdout или file
+	Endpoint    string  `yaml:"endpoint"`     // URL OTLP/HTTP; пусто — из OTEL_EXPORTER_OTLP_ENDPOINT или localhost:4318
+	File        string  `yaml:"file"`         // файл для экспортёра file
+	ServiceName string  `yaml:"service_name"` // service.name в ресурсах трассировки
+	SampleRatio float64 `yaml:"sample_ratio"` // доля сохраняемых трасс от 0 до 1
+}
+
+// AuthConfig — проверка JWT. HS256 включается секретом, RS256 — набором ключей JWKS из файла или по URL
+type AuthConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	HMACSecret string `yaml:"hmac_secret"` // секрет HS256, не короче 32 байт
+	JWKSFile   string `yaml:"jwks_file"`
+	JWKSURL    string `yaml:"jwks_url"`
+	Issuer     string `yaml:"issuer"`   // ожидаемый iss; пусто — не проверяется
+	Audience   string `yaml:"audience"` // ожидаемый aud; пусто — не проверяется
+}
+
+// APIKeysConfig — проверка API-ключей сервисов, включается независимо от JWT.
+// При auth.enabled ключи принимаются и без api_keys.enabled, как раньше
+type APIKeysConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	BootstrapKey string `yaml:"bootstrap_key"` // ключ администратора для выпуска первых ключей через /api-keys, не короче 32 байт
+}
+
+// RateLimitConfig — ограничение частоты запросов к API алгоритмом token bucket
+type RateLimitConfig struct {
+	Enabled   bool            `yaml:"enabled"`
+	PerIP     RateLimitBudget `yaml:"per_ip"`     // на IP-адрес клиента
+	PerClient RateLimitBudget `yaml:"per_client"` // на пользователя или API-ключ
+	Expensive RateLimitBudget `yaml:"expensive"`  // отдельно на каждый дорогой маршрут (список подписок, сумма) для каждого клиента
+}
+
+// RateLimitBudget — Rate запросов в секунду в среднем и не больше Burst подряд
+type RateLimitBudget struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+// IdempotencyConfig — хранение ответов на запросы с заголовком Idempotency-Key
+type IdempotencyConfig struct {
+	TTL         time.Duration `yaml:"ttl"`          // сколько помнить ключ и повторять сохранённый ответ
+	LockTimeout time.Duration `yaml:"lock_timeout"` // через сколько незавершённый запрос с тем же ключом считается брошенным
+}
+
+// ExchangeConfig — курсы валют для пересчёта сумм
+type ExchangeConfig struct {
+	RatesFile string `yaml:"rates_file"` // файл курсов ЦБ (.xml) или CSV (.csv), загружаемый при старте; пусто — не загружать
+}
+
+// MetricsConfig — метрики Prometheus. Они включают число подписок и траты по сервисам,
+// поэтому отдаются на отдельном адресе, а не на публичном порту API
+type MetricsConfig struct {
+	Addr string `yaml:"addr"` // адрес /metrics, например :9090 или 127.0.0.1:9090; пусто — метрики не отдаются
+}
+
+// minHMACSecretLen — минимальная длина секрета HS256 (RFC 7518, раздел 3.2)
+const minHMACSecretLen = 32
+
+// minBootstrapKeyLen — минимальная длина ключа администратора из конфигурации
+const minBootstrapKeyLen = 32
+
+// Default возвращает конфигурацию по умолчанию
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:               8080,
+			ReadHeaderTimeout:  5 * time.Second,
+			ReadTimeout:        15 * time.Second,
+			WriteTimeout:       30 * time.Second,
+			IdleTimeout:        120 * time.Second,
+			ShutdownTimeout:    20 * time.Second,
+			ShutdownDrainDelay: 5 * time.Second,
+			MaxBodyBytes:       1 << 20,
+		},
+		Database: DatabaseConfig{Driver: "postgres", SQLitePath: "rest-service.db", ConnectTimeout: time.Minute},
+		CORS:     CORSConfig{AllowOrigins: []string{"*"}},
+		Log:      LogConfig{Level: "info", Format: LogFormatJSON},
+		Tracing:  TracingConfig{Exporter: TracingExporterNone, ServiceName: "rest-service", SampleRatio: 1},
+		// Лимиты выключены по умолчанию, чтобы обновление не начинало отвечать 429 без изменения конфигурации.
+		// Бюджет дорогих маршрутов рассчитан на постраничный обход списка подписок
+		RateLimit: RateLimitConfig{
+			PerIP:     RateLimitBudget{Rate: 20, Burst: 40},
+			PerClient: RateLimitBudget{Rate: 10, Burst: 20},
+			Expensive: RateLimitBudget{Rate: 5, Burst: 20},
+		},
+		Idempotency: IdempotencyConfig{TTL: 24 * time.Hour, LockTimeout: time.Minute},
+		Metrics:     MetricsConfig{Addr: ":9090"},
+	}
+}
+
+// Load собирает конфигурацию из файла, окружения и аргументов командной строки и проверяет её
+func Load(args []string) (*Config, error) {
+	return load(args, os.LookupEnv)
+}
+
+// option описывает параметр, который можно задать переменной окружения и флагом
+type option struct {
+	env   string
+	flag  string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+var options = []option{
+	{"PORT", "port", "HTTP port", func(c *Config, v string) error { return setInt(&c.Server.Port, v) }},
+	{"SERVER_READ_HEADER_TIMEOUT", "read-header-timeout", "time to read request headers", func(c *Config, v string) error { return setDuration(&c.Server.ReadHeaderTimeout, v) }},
+	{"SERVER_READ_TIMEOUT", "read-timeout", "time to read the whole request", func(c *Config, v string) error { return setDuration(&c.Server.ReadTimeout, v) }},
+	{"SERVER_WRITE_TIMEOUT", "write-timeout", "time to write the response", func(c *Config, v string) error { return setDuration(&c.Server.WriteTimeout, v) }},
+	{"SERVER_IDLE_TIMEOUT", "idle-timeout", "keep-alive connection idle timeout", func(c *Config, v string) error { return setDuration(&c.Server.IdleTimeout, v) }},
+	{"SERVER_SHUTDOWN_TIMEOUT", "shutdown-timeout", "time to wait for in-flight requests on shutdown", func(c *Config, v string) error { return setDuration(&c.Server.ShutdownTimeout, v) }},
+	{"SERVER_SHUTDOWN_DRAIN_DELAY", "shutdown-drain-delay", "time to keep serving with a failing readiness probe before shutdown", func(c *Config, v string) error { return setDuration(&c.Server.ShutdownDrainDelay, v) }},
+	{"SERVER_MAX_BODY_BYTES", "max-body-bytes", "maximum request body size in bytes", func(c *Config, v string) error { return setInt64(&c.Server.MaxBodyBytes, v) }},
+	{"SERVER_TRUSTED_PROXIES", "trusted-proxies", "comma-separated list of trusted proxy IPs or CIDRs", func(c *Config, v string) error { c.Server.TrustedProxies = splitList(v); return nil }},
+	{"DB_DRIVER", "db-driver", "storage driver: postgres, sqlite or memory", func(c *Config, v string) error { c.Database.Driver = v; return nil }},
+	{"DATABASE_URL", "database-url", "PostgreSQL connection string", func(c *Config, v string) error { c.Database.URL = v; return nil }},
+	{"SQLITE_PATH", "sqlite-path", "SQLite database file", func(c *Config, v string) error { c.Database.SQLitePath = v; return nil }},
+	{"MIGRATIONS_DIR", "migrations-dir", "directory with migrations (embedded ones are used when empty)", func(c *Config, v string) error { c.Database.MigrationsDir = v; return nil }},
+	{"DB_CONNECT_TIMEOUT", "db-connect-timeout", "how long to retry the initial database connection", func(c *Config, v string) error { return setDuration(&c.Database.ConnectTimeout, v) }},
+	{"CORS_ALLOW_ORIGINS", "cors-allow-origins", "comma-separated list of allowed CORS origins", func(c *Config, v string) error { c.CORS.AllowOrigins = splitList(v); return nil }},
+	{"LOG_LEVEL", "log-level", "log level: debug, info, warn, error", func(c *Config, v string) error { c.Log.Level = v; return nil }},
+	{"LOG_FORMAT", "log-format", "log format: json, text or logfmt", func(c *Config, v string) error { c.Log.Format = v; return nil }},
+	{"AUTH_ENABLED", "auth-enabled", "require JWT bearer tokens", func(c *Config, v string) error { return setBool(&c.Auth.Enabled, v) }},
+	{"AUTH_HMAC_SECRET", "auth-hmac-secret", "HS256 secret", func(c *Config, v string) error { c.Auth.HMACSecret = v; return nil }},
+	{"AUTH_JWKS_FILE", "auth-jwks-file", "JWKS file with RS256 public keys", func(c *Config, v string) error { c.Auth.JWKSFile = v; return nil }},
+	{"AUTH_JWKS_URL", "auth-jwks-url", "JWKS URL with RS256 public keys", func(c *Config, v string) error { c.Auth.JWKSURL = v; return nil }},
+	{"AUTH_ISSUER", "auth-issuer", "expected token issuer (iss)", func(c *Config, v string) error { c.Auth.Issuer = v; return nil }},
+	{"AUTH_AUDIENCE", "auth-audience", "expected token audience (aud)", func(c *Config, v string) error { c.Auth.Audience = v; return nil }},
+	{"API_KEYS_ENABLED", "api-keys-enabled", "require API keys (X-API-Key), independently of JWT", func(c *Config, v string) error { return setBool(&c.APIKeys.Enabled, v) }},
+	{"API_KEYS_BOOTSTRAP_KEY", "api-keys-bootstrap-key", "admin API key for issuing the first keys", func(c *Config, v string) error { c.APIKeys.BootstrapKey = v; return nil }},
+	{"RATE_LIMIT_ENABLED", "rate-limit-enabled", "limit request rate per client IP and per user or API key", func(c *Config, v string) error { return setBool(&c.RateLimit.Enabled, v) }},
+	{"RATE_LIMIT_IP_RATE", "rate-limit-ip-rate", "requests per second per client IP", func(c *Config, v string) error { return setFloat(&c.RateLimit.PerIP.Rate, v) }},
+	{"RATE_LIMIT_IP_BURST", "rate-limit-ip-burst", "request burst per client IP", func(c *Config, v string) error { return setInt(&c.RateLimit.PerIP.Burst, v) }},
+	{"RATE_LIMIT_CLIENT_RATE", "rate-limit-client-rate", "requests per second per user or API key", func(c *Config, v string) error { return setFloat(&c.RateLimit.PerClient.Rate, v) }},
+	{"RATE_LIMIT_CLIENT_BURST", "rate-limit-client-burst", "request burst per user or API key", func(c *Config, v string) error { return setInt(&c.RateLimit.PerClient.Burst, v) }},
+	{"RATE_LIMIT_EXPENSIVE_RATE", "rate-limit-expensive-rate", "requests per second per client to each expensive route", func(c *Config, v string) error { return setFloat(&c.RateLimit.Expensive.Rate, v) }},
+	{"RATE_LIMIT_EXPENSIVE_BURST", "rate-limit-expensive-burst", "request burst per client to each expensive route", func(c *Config, v string) error { return setInt(&c.RateLimit.Expensive.Burst, v) }},
+	{"IDEMPOTENCY_TTL", "idempotency-ttl", "how long to keep responses to requests with Idempotency-Key", func(c *Config, v string) error { return setDuration(&c.Idempotency.TTL, v) }},
+	{"IDEMPOTENCY_LOCK_TIMEOUT", "idempotency-lock-timeout", "how long an unfinished request blocks retries with the same Idempotency-Key", func(c *Config, v string) error { return setDuration(&c.Idempotency.LockTimeout, v) }},
+	{"EXCHANGE_RATES_FILE", "exchange-rates-file", "CBR XML or CSV file with exchange rates to load on startup", func(c *Config, v string) error { c.Exchange.RatesFile = v; return nil }},
+	{"METRICS_ADDR", "metrics-addr", "listen address of the Prometheus /metrics endpoint, separate from the API port", func(c *Config, v string) error { c.Metrics.Addr = v; return nil }},
+	{"TRACING_EXPORTER", "tracing-exporter", "trace exporter: none, otlp, stdout or file", func(c *Config, v string) error { c.Tracing.Exporter = v; return nil }},
+	{"TRACING_ENDPOINT", "tracing-endpoint", "OTLP/HTTP endpoint URL", func(c *Config, v string) error { c.Tracing.Endpoint = v; return nil }},
+	{"TRACING_FILE", "tracing-file", "file for the file trace exporter", func(c *Config, v string) error { c.Tracing.File = v; return nil }},
+	{"TRACING_SERVICE_NAME", "tracing-service-name", "service name reported in traces", func(c *Config, v string) error { c.Tracing.ServiceName = v; return nil }},
+	{"TRACING_SAMPLE_RATIO", "tracing-sample-ratio", "fraction of traces to sample, 0 to 1", func(c *Config, v string) error { return setFloat(&c.Tracing.SampleRatio, v) }},
+}
+
+func load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	fs := flag.NewFlagSet("rest-service", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to YAML config file (env CONFIG_FILE)")
+	flagValues := make(map[string]*string, len(options))
+	for _, o := range options {
+		flagValues[o.flag] = fs.String(o.flag, "", o.usage+" (env "+o.env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+
+	path := *configFile
+	if path == "" {
+		path, _ = lookupEnv("CONFIG_FILE")
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	}
+
+	for _, o := range options {
+		value, ok, err := lookupEnvOrFile(o.env, lookupEnv)
+		if err != nil {
+			return nil, err
+		}
+		if ok && value != "" {
+			if err := o.set(&cfg, value); err != nil {
+				return nil, fmt.Errorf("%s: %w", o.env, err)
+			}
+		}
+	}
+
+	// Флаги применяются последними и только если заданы явно
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, o := range options {
+			if o.flag == f.Name && flagErr == nil {
+				if err := o.set(&cfg, *flagValues[o.flag]); err != nil {
+					flagErr = fmt.Errorf("-%s: %w", o.flag, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// lookupEnvOrFile читает переменную NAME или, если задана NAME_FILE, содержимое указанного файла.
+// Пустые переменные считаются незаданными
+func lookupEnvOrFile(name string, lookupEnv func(string) (string, bool)) (string, bool, error) {
+	value, ok := lookupEnv(name)
+	path, fileOK := lookupEnv(name + "_FILE")
+	if !fileOK {
+		return value, ok, nil
+	}
+	if ok {
+		return "", false, fmt.Errorf("both %s and %s_FILE are set", name, name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+// Validate проверяет конфигурацию
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port must be between 1 and 65535, got %d", c.Server.Port))
+	}
+	for name, d := range map[string]time.Duration{
+		"server.read_header_timeout":  c.Server.ReadHeaderTimeout,
+		"server.read_timeout":         c.Server.ReadTimeout,
+		"server.write_timeout":        c.Server.WriteTimeout,
+		"server.idle_timeout":         c.Server.IdleTimeout,
+		"server.shutdown_timeout":     c.Server.ShutdownTimeout,
+		"server.shutdown_drain_delay": c.Server.ShutdownDrainDelay,
+		"database.connect_timeout":    c.Database.ConnectTimeout,
+	} {
+		if d < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %s", name, d))
+		}
+	}
+	if c.Server.MaxBodyBytes <= 0 {
+		errs = append(errs, fmt.Errorf("server.max_body_bytes must be positive, got %d", c.Server.MaxBodyBytes))
+	}
+	switch c.Database.Driver {
+	case "postgres":
+		if c.Database.URL == "" {
+			errs = append(errs, errors.New("database.url is required for postgres driver"))
+		}
+	case "sqlite":
+		if c.Database.SQLitePath == "" {
+			errs = append(errs, errors.New("database.sqlite_path is required for sqlite driver"))
+		}
+	case "memory":
+	default:
+		errs = append(errs, fmt.Errorf("database.driver must be postgres, sqlite or memory, got %q", c.Database.Driver))
+	}
+	if len(c.CORS.AllowOrigins) == 0 {
+		errs = append(errs, errors.New("cors.allow_origins must not be empty"))
+	}
+	if _, err := log.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	switch c.Log.Format {
+	case LogFormatJSON, LogFormatText, LogFormatLogfmt:
+	default:
+		errs = append(errs, fmt.Errorf("log.format must be json, text or logfmt, got %q", c.Log.Format))
+	}
+	switch c.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterOTLP, TracingExporterStdout:
+	case TracingExporterFile:
+		if c.Tracing.File == "" {
+			errs = append(errs, errors.New("tracing.file is required for file exporter"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter must be none, otlp, stdout or file, got %q", c.Tracing.Exporter))
+	}
+	if c.Auth.Enabled {
+		if c.Auth.HMACSecret == "" && c.Auth.JWKSFile == "" && c.Auth.JWKSURL == "" {
+			errs = append(errs, errors.New("auth: hmac_secret, jwks_file or jwks_url is required when auth is enabled"))
+		}
+		if c.Auth.JWKSFile != "" && c.Auth.JWKSURL != "" {
+			errs = append(errs, errors.New("auth: jwks_file and jwks_url are mutually exclusive"))
+		}
+		if c.Auth.HMACSecret != "" && len(c.Auth.HMACSecret) < minHMACSecretLen {
+			errs = append(errs, fmt.Errorf("auth.hmac_secret must be at least %d bytes", minHMACSecretLen))
+		}
+	}
+	if c.APIKeys.BootstrapKey != "" {
+		if !c.AcceptsAPIKeys() {
+			errs = append(errs, errors.New("api_keys.bootstrap_key requires api_keys.enabled"))
+		}
+		if len(c.APIKeys.BootstrapKey) < minBootstrapKeyLen {
+			errs = append(errs, fmt.Errorf("api_keys.bootstrap_key must be at least %d bytes", minBootstrapKeyLen))
+		}
+	}
+	if c.RateLimit.Enabled {
+		for name, b := range map[string]RateLimitBudget{
+			"rate_limit.per_ip":     c.RateLimit.PerIP,
+			"rate_limit.per_client": c.RateLimit.PerClient,
+			"rate_limit.expensive":  c.RateLimit.Expensive,
+		} {
+			if b.Rate <= 0 || b.Burst < 1 {
+				errs = append(errs, fmt.Errorf("%s: rate must be positive and burst at least 1, got rate %v and burst %d", name, b.Rate, b.Burst))
+			}
+		}
+	}
+	if c.Idempotency.TTL <= 0 || c.Idempotency.LockTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("idempotency: ttl and lock_timeout must be positive, got %v and %v", c.Idempotency.TTL, c.Idempotency.LockTimeout))
+	}
+	if c.Metrics.Addr != "" {
+		if _, port, err := net.SplitHostPort(c.Metrics.Addr); err != nil {
+			errs = append(errs, fmt.Errorf("metrics.addr: %w", err))
+		} else if port == strconv.Itoa(c.Server.Port) {
+			errs = append(errs, fmt.Errorf("metrics.addr must not use the API port %d", c.Server.Port))
+		}
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing.sample_ratio must be between 0 and 1, got %v", c.Tracing.SampleRatio))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// AcceptsAPIKeys сообщает, проверяются ли API-ключи: они включаются отдельно или вместе с JWT
+func (c *Config) AcceptsAPIKeys() bool {
+	return c.APIKeys.Enabled || c.Auth.Enabled
+}
+
+// Addr возвращает адрес, на котором слушает HTTP-сервер
+func (c ServerConfig) Addr() string {
+	return ":" + strconv.Itoa(c.Port)
+}
+
+// RedactedURL возвращает строку подключения без пароля — её можно писать в лог
+func (c DatabaseConfig) RedactedURL() string {
+	if c.URL == "" {
+		return ""
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || u.Scheme == "" {
+		// Формат key=value или нераспознанная строка: не рискуем и скрываем целиком
+		return "[REDACTED]"
+	}
+	if q := u.Query(); q.Has("password") {
+		q.Set("password", "xxxxx")
+		u.RawQuery = q.Encode()
+	}
+	return u.Redacted()
+}
+
+// LogFields возвращает безопасные для логирования параметры конфигурации
+func (c *Config) LogFields() log.Fields {
+	return log.Fields{
+		"port":                 c.Server.Port,
+		"shutdown_timeout":     c.Server.ShutdownTimeout.String(),
+		"shutdown_drain_delay": c.Server.ShutdownDrainDelay.String(),
+		"max_body_bytes":       c.Server.MaxBodyBytes,
+		"db_driver":            c.Database.Driver,
+		"database_url":         c.Database.RedactedURL(),
+		"sqlite_path":          c.Database.SQLitePath,
+		"migrations_dir":       c.Database.MigrationsDir,
+		"db_connect_timeout":   c.Database.ConnectTimeout.String(),
+		"cors_origins":         c.CORS.AllowOrigins,
+		"log_level":            c.Log.Level,
+		"log_format":           c.Log.Format,
+		"tracing_exporter":     c.Tracing.Exporter,
+		"auth_enabled":         c.Auth.Enabled,
+		"auth_jwks_url":        c.Auth.JWKSURL,
+		"api_keys_enabled":     c.AcceptsAPIKeys(),
+		"rate_limit_enabled":   c.RateLimit.Enabled,
+		"trusted_proxies":      c.Server.TrustedProxies,
+		"idempotency_ttl":      c.Idempotency.TTL.String(),
+		"exchange_rates_file":  c.Exchange.RatesFile,
+		"metrics_addr":         c.Metrics.Addr,
+	}
+}
+
+func setInt(dst *int, value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", value)
+	}
+	*dst = n
+	return nil
+}
+
+func setInt64(dst *int64, value string) error {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", value)
+	}
+	*dst = n
+	return nil
+}
+
+func setBool(dst *bool, value string) error {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid boolean %q", value)
+	}
+	*dst = b
+	return nil
+}
+
+func setFloat(dst *float64, value string) error {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", value)
+	}
+	*dst = f
+	return nil
+}
+
+func setDuration(dst *time.Duration, value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", value)
+	}
+	*dst = d
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,156 @@
+// Package exchange разбирает файлы курсов валют и пересчитывает суммы между валютами
+package exchange
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"rest-service/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Format — формат файла с курсами
+type Format string
+
+const (
+	// FormatCBR — ежедневные курсы ЦБ РФ (XML_daily.asp): один файл на дату, курс за Nominal единиц валюты
+	FormatCBR Format = "cbr"
+	// FormatCSV — строки currency,date,rate[,nominal] с заголовком; date в формате YYYY-MM-DD
+	FormatCSV Format = "csv"
+)
+
+const dateLayout = "2006-01-02"
+
+// FormatFromPath определяет формат по расширению файла: .xml — FormatCBR, .csv — FormatCSV
+func FormatFromPath(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xml":
+		return FormatCBR, nil
+	case ".csv":
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("unknown exchange rates file format %q, expected .xml or .csv", path)
+}
+
+// Parse читает курсы в формате format
+func Parse(r io.Reader, format Format) ([]models.ExchangeRate, error) {
+	switch format {
+	case FormatCBR:
+		return ParseCBR(r)
+	case FormatCSV:
+		return ParseCSV(r)
+	}
+	return nil, fmt.Errorf("unknown exchange rates format %q", format)
+}
+
+type cbrValCurs struct {
+	Date   string `xml:"Date,attr"`
+	Valute []struct {
+		CharCode string `xml:"CharCode"`
+		Nominal  string `xml:"Nominal"`
+		Value    string `xml:"Value"`
+	} `xml:"Valute"`
+}
+
+// ParseCBR читает курсы из XML в формате ЦБ РФ. Файлы ЦБ приходят в windows-1251,
+// а числа в них записаны с десятичной запятой
+func ParseCBR(r io.Reader) ([]models.ExchangeRate, error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		if strings.EqualFold(charset, "windows-1251") {
+			return charmap.Windows1251.NewDecoder().Reader(input), nil
+		}
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	var doc cbrValCurs
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid CBR XML: %w", err)
+	}
+	date, err := time.Parse("02.01.2006", doc.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CBR XML: bad Date %q, expected DD.MM.YYYY", doc.Date)
+	}
+
+	rates := make([]models.ExchangeRate, 0, len(doc.Valute))
+	for _, v := range doc.Valute {
+		rate, err := newRate(v.CharCode, date, v.Value, v.Nominal)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CBR XML: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// ParseCSV читает курсы из CSV с заголовком currency,date,rate и необязательной колонкой nominal.
+// Порядок колонок определяется по заголовку
+func ParseCSV(r io.Reader) ([]models.ExchangeRate, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: cannot read header: %w", err)
+	}
+	columns := map[string]int{"nominal": -1}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"currency", "date", "rate"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("invalid CSV: missing %s column", name)
+		}
+	}
+	cr.FieldsPerRecord = len(header)
+
+	var rates []models.ExchangeRate
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rates, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		date, err := time.Parse(dateLayout, record[columns["date"]])
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: line %d: bad date %q, expected YYYY-MM-DD", line, record[columns["date"]])
+		}
+		nominal := ""
+		if i := columns["nominal"]; i >= 0 {
+			nominal = record[i]
+		}
+		rate, err := newRate(record[columns["currency"]], date, record[columns["rate"]], nominal)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: line %d: %w", line, err)
+		}
+		rates = append(rates, rate)
+	}
+}
+
+// newRate проверяет код валюты и приводит курс за nominal единиц к курсу за одну единицу
+func newRate(currency string, date time.Time, value, nominal string) (models.ExchangeRate, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if len(currency) != 3 || strings.Trim(currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return models.ExchangeRate{}, fmt.Errorf("bad currency code %q", currency)
+	}
+	rate, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(value), ",", ".", 1), 64)
+	if err != nil || rate <= 0 {
+		return models.ExchangeRate{}, fmt.Errorf("bad rate %q for %s", value, currency)
+	}
+	if nominal = strings.TrimSpace(nominal); nominal != "" {
+		n, err := strconv.Atoi(nominal)
+		if err != nil || n <= 0 {
+			return models.ExchangeRate{}, fmt.Errorf("bad nominal %q for %s", nominal, currency)
+		}
+		rate /= float64(n)
+	}
+	return models.ExchangeRate{Currency: currency, Date: date, Rate: rate}, nil
+}
@@ -0,0 +1,87 @@
+package exchange
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+
+	"rest-service/internal/models"
+)
+
+const cbrDaily = `<?xml version="1.0" encoding="windows-1251"?>
+<ValCurs Date="15.01.2025" name="Foreign Currency Market">
+<Valute ID="R01235">
+	<NumCode>840</NumCode>
+	<CharCode>USD</CharCode>
+	<Nominal>1</Nominal>
+	<Name>Доллар США</Name>
+	<Value>102,9125</Value>
+	<VunitRate>102,9125</VunitRate>
+</Valute>
+<Valute ID="R01335">
+	<NumCode>398</NumCode>
+	<CharCode>KZT</CharCode>
+	<Nominal>100</Nominal>
+	<Name>Тенге</Name>
+	<Value>19,5512</Value>
+	<VunitRate>0,195512</VunitRate>
+</Valute>
+</ValCurs>`
+
+func TestParseCBR(t *testing.T) {
+	encoded, err := charmap.Windows1251.NewEncoder().String(cbrDaily)
+	require.NoError(t, err)
+
+	rates, err := Parse(strings.NewReader(encoded), FormatCBR)
+	require.NoError(t, err)
+	date := time.Date(2025, time.January, 15, 0, 0, 0, 0, time.UTC)
+	require.Len(t, rates, 2)
+	assert.Equal(t, models.ExchangeRate{Currency: "USD", Date: date, Rate: 102.9125}, rates[0])
+	// Курс тенге дан за 100 единиц
+	assert.Equal(t, "KZT", rates[1].Currency)
+	assert.InDelta(t, 0.195512, rates[1].Rate, 1e-9)
+
+	_, err = ParseCBR(strings.NewReader(`<ValCurs Date="2025-01-15"></ValCurs>`))
+	assert.ErrorContains(t, err, "bad Date")
+	_, err = ParseCBR(strings.NewReader(`<ValCurs Date="15.01.2025"><Valute><CharCode>USD</CharCode><Nominal>1</Nominal><Value>n/a</Value></Valute></ValCurs>`))
+	assert.ErrorContains(t, err, "bad rate")
+}
+
+func TestParseCSV(t *testing.T) {
+	rates, err := ParseCSV(strings.NewReader("date,currency,rate,nominal\n2025-01-01,usd,100.5,1\n2025-02-01,KZT,\"19,55\",100\n"))
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+	assert.Equal(t, models.ExchangeRate{Currency: "USD", Date: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), Rate: 100.5}, rates[0])
+	assert.InDelta(t, 0.1955, rates[1].Rate, 1e-9)
+
+	rates, err = ParseCSV(strings.NewReader("currency,date,rate\nEUR,2025-01-01,110\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 110.0, rates[0].Rate)
+
+	for input, msg := range map[string]string{
+		"currency,rate\nEUR,110\n":                         "missing date column",
+		"currency,date,rate\nEUR,01.01.2025,110\n":         "line 2: bad date",
+		"currency,date,rate\nEURO,2025-01-01,110\n":        "bad currency code",
+		"currency,date,rate\nEUR,2025-01-01,-1\n":          "bad rate",
+		"currency,date,rate,nominal\nEUR,2025-01-01,1,0\n": "bad nominal",
+		"currency,date,rate\nEUR,2025-01-01\n":             "wrong number of fields",
+	} {
+		_, err := ParseCSV(strings.NewReader(input))
+		assert.ErrorContains(t, err, msg, input)
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	format, err := FormatFromPath("/data/XML_daily.XML")
+	require.NoError(t, err)
+	assert.Equal(t, FormatCBR, format)
+	format, err = FormatFromPath("rates.csv")
+	require.NoError(t, err)
+	assert.Equal(t, FormatCSV, format)
+	_, err = FormatFromPath("rates.json")
+	assert.Error(t, err)
+}
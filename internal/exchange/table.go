@@ -0,0 +1,59 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"rest-service/internal/models"
+	"sort"
+	"time"
+)
+
+// ErrNoRate — у валюты нет курса, действующего на нужную дату
+var ErrNoRate = errors.New("no exchange rate")
+
+// Table — курсы валют по датам, по которым пересчитываются суммы
+type Table struct {
+	rates map[string][]models.ExchangeRate // по валюте, по возрастанию даты
+}
+
+// NewTable строит таблицу из курсов в любом порядке. Из нескольких курсов одной валюты на одну дату действует последний
+func NewTable(rates []models.ExchangeRate) *Table {
+	t := &Table{rates: make(map[string][]models.ExchangeRate)}
+	for _, r := range rates {
+		t.rates[r.Currency] = append(t.rates[r.Currency], r)
+	}
+	for _, list := range t.rates {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Date.Before(list[j].Date) })
+	}
+	return t
+}
+
+// Rate возвращает цену единицы валюты в рублях, действующую на момент at. Курс рубля всегда равен 1
+func (t *Table) Rate(currency string, at time.Time) (float64, error) {
+	if currency == models.BaseCurrency {
+		return 1, nil
+	}
+	list := t.rates[currency]
+	// Первый курс, вступивший в силу позже at; действует предыдущий
+	i := sort.Search(len(list), func(i int) bool { return list[i].Date.After(at) })
+	if i == 0 {
+		return 0, fmt.Errorf("%w for %s on %s", ErrNoRate, currency, at.Format(dateLayout))
+	}
+	return list[i-1].Rate, nil
+}
+
+// Convert пересчитывает amount из валюты from в валюту to по курсам, действующим на момент at
+func (t *Table) Convert(amount float64, from, to string, at time.Time) (float64, error) {
+	if from == to {
+		return amount, nil
+	}
+	fromRate, err := t.Rate(from, at)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := t.Rate(to, at)
+	if err != nil {
+		return 0, err
+	}
+	return amount * fromRate / toRate, nil
+}
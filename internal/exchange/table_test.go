@@ -0,0 +1,43 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rest-service/internal/models"
+)
+
+func TestTable_Convert(t *testing.T) {
+	day := func(month time.Month, d int) time.Time { return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC) }
+	table := NewTable([]models.ExchangeRate{
+		{Currency: "USD", Date: day(time.February, 1), Rate: 90},
+		{Currency: "USD", Date: day(time.January, 1), Rate: 100},
+		{Currency: "EUR", Date: day(time.January, 1), Rate: 110},
+	})
+
+	rate, err := table.Rate("USD", day(time.January, 31))
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, rate)
+	// Новый курс действует с даты вступления в силу
+	rate, err = table.Rate("USD", day(time.February, 1))
+	require.NoError(t, err)
+	assert.Equal(t, 90.0, rate)
+
+	amount, err := table.Convert(10, "USD", models.BaseCurrency, day(time.March, 1))
+	require.NoError(t, err)
+	assert.Equal(t, 900.0, amount)
+	amount, err = table.Convert(11, "EUR", "USD", day(time.January, 1))
+	require.NoError(t, err)
+	assert.InDelta(t, 12.1, amount, 1e-9)
+	amount, err = table.Convert(5, "GBP", "GBP", day(time.January, 1))
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, amount)
+
+	_, err = table.Convert(10, "USD", models.BaseCurrency, day(time.January, 1).AddDate(0, 0, -1))
+	assert.ErrorIs(t, err, ErrNoRate)
+	_, err = table.Convert(10, models.BaseCurrency, "GBP", day(time.January, 1))
+	assert.ErrorIs(t, err, ErrNoRate)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"rest-service/internal/logging"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// Ключи gin-контекста с ID пользователя или API-ключа, от имени которого выполняется запрос
+const (
+	userIDKey   = "user_id"
+	apiKeyIDKey = "api_key_id"
+)
+
+// quietRoutes — пробы, они вызываются постоянно, поэтому пишутся в лог только на уровне debug
+var quietRoutes = map[string]bool{"/healthz": true, "/readyz": true}
+
+// AccessLog пишет одну строку на запрос: метод, маршрут, статус, длительность, размеры тела и пользователя.
+// Должен стоять после RequestID и до ErrorMiddleware, чтобы видеть итоговый статус ответа
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		fields := log.Fields{
+			"method":     c.Request.Method,
+			"path":       c.Request.URL.Path,
+			"route":      c.FullPath(),
+			"status":     status,
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"bytes_in":   max(c.Request.ContentLength, 0),
+			"bytes_out":  max(c.Writer.Size(), 0),
+			"client_ip":  c.ClientIP(),
+			"user_agent": c.Request.UserAgent(),
+		}
+		if user := c.GetString(userIDKey); user != "" {
+			fields[userIDKey] = user
+		}
+		if key := c.GetInt(apiKeyIDKey); key != 0 {
+			fields[apiKeyIDKey] = key
+		}
+
+		entry := logging.FromContext(c.Request.Context()).WithFields(fields)
+		switch {
+		case status >= http.StatusInternalServerError:
+			entry.Warn("Request completed")
+		case quietRoutes[c.FullPath()]:
+			entry.Debug("Request completed")
+		default:
+			entry.Info("Request completed")
+		}
+	}
+}
+
+// Recovery перехватывает панику в обработчике, пишет её в лог со стеком
+// и передаёт ErrorMiddleware как внутреннюю ошибку. Должен стоять после ErrorMiddleware
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				if r == http.ErrAbortHandler {
+					panic(r)
+				}
+				logging.FromContext(c.Request.Context()).WithFields(log.Fields{
+					"panic": fmt.Sprint(r),
+					"stack": string(debug.Stack()),
+				}).Error("Panic while handling request")
+				_ = c.Error(fmt.Errorf("panic: %v", r))
+				c.Abort()
+			}
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"rest-service/internal/logging"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLog перенаправляет общий логгер в буфер на время теста
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetFormatter(&log.JSONFormatter{})
+	level := log.GetLevel()
+	log.SetLevel(log.DebugLevel)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stdout)
+		log.SetLevel(level)
+	})
+	return &buf
+}
+
+func logEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	buf := captureLog(t)
+
+	router := gin.New()
+	router.Use(RequestID(), AccessLog(), ErrorMiddleware(), Recovery())
+	router.GET("/subscriptions/:id", func(c *gin.Context) {
+		c.Set(userIDKey, "60601fee-2bf1-4721-ae6f-7636e79a0cba")
+		logging.FromContext(c.Request.Context()).Info("Inside handler")
+		c.JSON(http.StatusOK, gin.H{"id": 1})
+	})
+
+	req, _ := http.NewRequest("GET", "/subscriptions/1", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	entries := logEntries(t, buf)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "req-42", entries[0]["request_id"]) // логгер запроса доступен обработчику
+
+	access := entries[1]
+	assert.Equal(t, "Request completed", access["msg"])
+	assert.Equal(t, "req-42", access["request_id"])
+	assert.Equal(t, "/subscriptions/:id", access["route"])
+	assert.Equal(t, float64(200), access["status"])
+	assert.Equal(t, float64(w.Body.Len()), access["bytes_out"])
+	assert.Equal(t, "60601fee-2bf1-4721-ae6f-7636e79a0cba", access["user_id"])
+	assert.Contains(t, access, "latency_ms")
+}
+
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	buf := captureLog(t)
+
+	router := gin.New()
+	router.Use(RequestID(), AccessLog(), ErrorMiddleware(), Recovery())
+	router.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	req, _ := http.NewRequest("GET", "/panic", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+	assert.NotContains(t, w.Body.String(), "boom")
+
+	entries := logEntries(t, buf)
+	assert.Equal(t, "boom", entries[0]["panic"])
+	assert.Equal(t, float64(500), entries[len(entries)-1]["status"])
+}
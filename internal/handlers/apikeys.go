@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"rest-service/internal/auth"
+	"rest-service/internal/models"
+	"rest-service/internal/repository"
+	"rest-service/internal/validation"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	repo repository.APIKeyRepository
+}
+
+func NewAPIKeyHandler(repo repository.APIKeyRepository) *APIKeyHandler {
+	validation.Register()
+	return &APIKeyHandler{repo: repo}
+}
+
+// APIKeyRequest — параметры выпускаемого API-ключа
+type APIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=255"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,oneof=subscriptions:read subscriptions:write reports:read admin"`
+	ExpiresAt *time.Time `json:"expires_at"` // RFC 3339; не задано — бессрочный ключ
+}
+
+// IssuedAPIKey — API-ключ вместе с его значением. Значение возвращается только при выпуске и ротации
+type IssuedAPIKey struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// apiKeyError уточняет ответ 404 для API-ключей
+func apiKeyError(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return &httpError{status: http.StatusNotFound, detail: "api key not found or revoked"}
+	}
+	return err
+}
+
+// Create godoc
+// @Summary Issue an API key
+// @Description Issue a key for service-to-service calls. The key itself is returned only once, store it right away. Requires the admin scope
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param key body APIKeyRequest true "Key name, scopes and optional expiry"
+// @Success 201 {object} IssuedAPIKey
+// @Failure 400 {object} Problem "malformed JSON"
+// @Failure 401 {object} Problem "missing or invalid credentials"
+// @Failure 403 {object} Problem "admin scope is required"
+// @Failure 422 {object} Problem "validation failed, per-field errors in fields"
+// @Failure 429 {object} Problem "rate limit exceeded, see Retry-After"
+// @Failure 500 {object} Problem "internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /api-keys [post]
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	ctx, endSpan := startSpan(c, "APIKeyHandler.Create")
+	defer endSpan()
+
+	var req APIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(bindError(err))
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.Error(validation.Errors{{Field: "expires_at", Message: "must be in the future"}})
+		return
+	}
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	stored := models.APIKey{Name: req.Name, Prefix: prefix, Hash: hash, Scopes: req.Scopes, ExpiresAt: req.ExpiresAt}
+	if _, err := h.repo.Create(ctx, &stored); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, IssuedAPIKey{APIKey: stored, Key: key})
+}
+
+// List godoc
+// @Summary List API keys
+// @Description List all API keys including revoked ones. Key values are never returned. Requires the admin scope
+// @Tags api-keys
+// @Produce json
+// @Success 200 {array} models.APIKey
+// @Failure 401 {object} Problem "missing or invalid credentials"
+// @Failure 403 {object} Problem "admin scope is required"
+// @Failure 429 {object} Problem "rate limit exceeded, see Retry-After"
+// @Failure 500 {object} Problem "internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /api-keys [get]
+func (h *APIKeyHandler) List(c *gin.Context) {
+	ctx, endSpan := startSpan(c, "APIKeyHandler.List")
+	defer endSpan()
+
+	keys, err := h.repo.List(ctx)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// Rotate godoc
+// @Summary Rotate an API key
+// @Description Replace the secret of an active key keeping its name, scopes and expiry. The old value stops working immediately. Requires the admin scope
+// @Tags api-keys
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} IssuedAPIKey
+// @Failure 400 {object} Problem "invalid id"
+// @Failure 401 {object} Problem "missing or invalid credentials"
+// @Failure 403 {object} Problem "admin scope is required"
+// @Failure 404 {object} Problem "api key not found, revoked or expired"
+// @Failure 429 {object} Problem "rate limit exceeded, see Retry-After"
+// @Failure 500 {object} Problem "internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /api-keys/{id}/rotate [post]
+func (h *APIKeyHandler) Rotate(c *gin.Context) {
+	ctx, endSpan := startSpan(c, "APIKeyHandler.Rotate")
+	defer endSpan()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(badRequest("invalid id"))
+		return
+	}
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if err := h.repo.Rotate(ctx, id, prefix, hash, time.Now()); err != nil {
+		c.Error(apiKeyError(err))
+		return
+	}
+	stored, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		c.Error(apiKeyError(err))
+		return
+	}
+	c.JSON(http.StatusOK, IssuedAPIKey{APIKey: *stored, Key: key})
+}
+
+// Revoke godoc
+// @Summary Revoke an API key
+// @Description Revoke a key permanently. Revoking an already revoked key is a no-op. Requires the admin scope
+// @Tags api-keys
+// @Param id path int true "API key ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} Problem "invalid id"
+// @Failure 401 {object} Problem "missing or invalid credentials"
+// @Failure 403 {object} Problem "admin scope is required"
+// @Failure 404 {object} Problem "api key not found"
+// @Failure 429 {object} Problem "rate limit exceeded, see Retry-After"
+// @Failure 500 {object} Problem "internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /api-keys/{id} [delete]
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	ctx, endSpan := startSpan(c, "APIKeyHandler.Revoke")
+	defer endSpan()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(badRequest("invalid id"))
+		return
+	}
+	if err := h.repo.Revoke(ctx, id, time.Now()); err != nil {
+		c.Error(apiKeyError(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
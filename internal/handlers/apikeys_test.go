@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rest-service/internal/auth"
+	"rest-service/internal/models"
+	"rest-service/internal/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyHandler_AuthDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keyRepo := repository.NewMemoryAPIKeyRepository()
+	keys := NewAPIKeyHandler(keyRepo)
+
+	// Как в main при выключенной аутентификации: Authenticate не подключён
+	router := newTestRouter()
+	admin := router.Group("/api-keys", RequireScope(auth.ScopeAdmin))
+	admin.POST("", keys.Create)
+	router.GET("/subscriptions", RequireScope(auth.ScopeSubscriptionsRead), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodPost, "/api-keys", strings.NewReader(`{"name":"backdoor","scopes":["admin"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "require authentication")
+
+	list, err := keyRepo.List(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	// Остальные действия анонимным запросам по-прежнему доступны
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/subscriptions", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAPIKeyHandler_Bootstrap(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bootstrap := "rsk_bootstrap-0123456789abcdef0123456789"
+	keyRepo := repository.NewMemoryAPIKeyRepository()
+	keys := NewAPIKeyHandler(keyRepo)
+
+	// Как в main только с API-ключами: JWT не настроен, первый ключ выпускается ключом из конфигурации
+	router := newTestRouter()
+	admin := router.Group("/api-keys", Authenticate(nil, auth.NewAPIKeyAuthenticator(keyRepo, bootstrap)), RequireScope(auth.ScopeAdmin))
+	admin.POST("", keys.Create)
+	admin.GET("", keys.List)
+
+	do := func(method, header, value, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api-keys", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, APIKeyHeader, bootstrap, `{"name":"ops","scopes":["admin"]}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var issued IssuedAPIKey
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &issued))
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, APIKeyHeader, issued.Key, "").Code)
+
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "", "", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "Authorization", testToken(t, uuid.New(), auth.RoleAdmin), "").Code)
+}
+
+func TestAPIKeyHandler_Lifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authenticator, err := auth.NewAuthenticator(auth.Options{HMACSecret: testSecret})
+	require.NoError(t, err)
+	keyRepo := repository.NewMemoryAPIKeyRepository()
+	authenticate := Authenticate(authenticator, auth.NewAPIKeyAuthenticator(keyRepo, ""))
+
+	subs := NewSubscriptionHandler(&MockSubscriptionRepository{
+		GetCostsFunc: func(ctx context.Context, filter repository.CostFilter) ([]repository.MonthlyCost, error) {
+			return []repository.MonthlyCost{monthlyCost(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), "RUB", 10000, 10000)}, nil
+		},
+		CreateFunc: func(ctx context.Context, sub *models.Subscription) (int, error) { return 1, nil },
+	}, repository.NewMemoryExchangeRateRepository())
+	keys := NewAPIKeyHandler(keyRepo)
+
+	router := newTestRouter()
+	api := router.Group("/subscriptions", authenticate)
+	api.POST("", RequireScope(auth.ScopeSubscriptionsWrite), subs.Create)
+	api.GET("/sum", RequireScope(auth.ScopeReportsRead), subs.GetSum)
+	admin := router.Group("/api-keys", authenticate, RequireScope(auth.ScopeAdmin))
+	admin.POST("", keys.Create)
+	admin.GET("", keys.List)
+	admin.POST("/:id/rotate", keys.Rotate)
+	admin.DELETE("/:id", keys.Revoke)
+
+	do := func(method, target string, header [2]string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(header[0], header[1])
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	bearer := func(token string) [2]string { return [2]string{"Authorization", token} }
+	apiKey := func(key string) [2]string { return [2]string{APIKeyHeader, key} }
+	adminToken := bearer(testToken(t, uuid.New(), auth.RoleAdmin))
+
+	// Обычный пользователь не может выпускать ключи
+	w := do(http.MethodPost, "/api-keys", bearer(testToken(t, uuid.New())), `{"name":"billing","scopes":["reports:read"]}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = do(http.MethodPost, "/api-keys", adminToken, `{"name":"billing","scopes":["reports:read","everything"],"expires_at":"2001-01-01T00:00:00Z"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "scopes[1]")
+
+	w = do(http.MethodPost, "/api-keys", adminToken, `{"name":"billing","scopes":["reports:read"]}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var issued IssuedAPIKey
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &issued))
+	assert.Equal(t, 1, issued.ID)
+	assert.True(t, strings.HasPrefix(issued.Key, issued.Prefix))
+	assert.NotContains(t, w.Body.String(), auth.HashAPIKey(issued.Key))
+
+	sum := "/subscriptions/sum?start=01-2025&end=12-2025&service_name=Netflix&user_id=" + uuid.NewString()
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, sum, apiKey(issued.Key), "").Code)
+	w = do(http.MethodPost, "/subscriptions", apiKey(issued.Key),
+		`{"service_name":"Netflix","price":500,"user_id":"`+uuid.NewString()+`","start_date":"07-2025"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "subscriptions:write")
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, sum, apiKey("rsk_forged"), "").Code)
+
+	// Ротация: старое значение перестаёт работать сразу
+	w = do(http.MethodPost, "/api-keys/1/rotate", adminToken, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var rotated IssuedAPIKey
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rotated))
+	assert.NotEqual(t, issued.Key, rotated.Key)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, sum, apiKey(issued.Key), "").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, sum, apiKey(rotated.Key), "").Code)
+
+	w = do(http.MethodGet, "/api-keys", adminToken, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), rotated.Key)
+	assert.Contains(t, w.Body.String(), `"last_used_at"`)
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/api-keys/1", adminToken, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, sum, apiKey(rotated.Key), "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/api-keys/1/rotate", adminToken, "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/api-keys/7", adminToken, "").Code)
+}
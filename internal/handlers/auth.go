@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"rest-service/internal/auth"
+	"rest-service/internal/logging"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// APIKeyHeader — заголовок с API-ключом сервиса
+const APIKeyHeader = "X-API-Key"
+
+// Authenticate требует API-ключ в заголовке X-API-Key или заголовок Authorization: Bearer <JWT>.
+// Пользователь или сервис кладётся в контекст запроса, а его ID добавляется в логгер запроса и access log.
+// Если tokens == nil, не принимаются JWT, а если keys == nil — API-ключи
+func Authenticate(tokens *auth.Authenticator, keys *auth.APIKeyAuthenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var p auth.Principal
+		var err error
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			if keys == nil {
+				err = fmt.Errorf("%w: api keys are not accepted", auth.ErrUnauthenticated)
+			} else {
+				p, err = keys.Authenticate(ctx, key)
+			}
+		} else {
+			scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+			switch {
+			case !ok || !strings.EqualFold(scheme, "Bearer") || token == "":
+				err = fmt.Errorf("%w: missing bearer token", auth.ErrUnauthenticated)
+			case tokens == nil:
+				err = fmt.Errorf("%w: bearer tokens are not accepted", auth.ErrUnauthenticated)
+			default:
+				p, err = tokens.Authenticate(ctx, token)
+			}
+		}
+		if err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+
+		fields := log.Fields{}
+		if p.IsService() {
+			fields[apiKeyIDKey] = p.APIKeyID
+			c.Set(apiKeyIDKey, p.APIKeyID)
+		} else {
+			fields[userIDKey] = p.UserID.String()
+			c.Set(userIDKey, p.UserID.String())
+		}
+		ctx = auth.WithPrincipal(ctx, p)
+		ctx = logging.WithLogger(ctx, logging.FromContext(ctx).WithFields(fields))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+var errAdminWithoutAuth = &httpError{status: http.StatusForbidden, detail: "admin operations require authentication to be enabled"}
+
+// RequireScope пропускает запрос, только если пользователю или API-ключу разрешено действие scope.
+// Анонимные запросы (аутентификация выключена) пропускаются без проверки, кроме действий admin:
+// иначе при выключенной аутентификации кто угодно мог бы выпустить ключ администратора,
+// который заработает, как только аутентификацию включат
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := auth.PrincipalFromContext(c.Request.Context())
+		if !ok && scope == auth.ScopeAdmin {
+			_ = c.Error(errAdminWithoutAuth)
+			c.Abort()
+			return
+		}
+		if ok && !p.HasScope(scope) {
+			_ = c.Error(&httpError{status: http.StatusForbidden, detail: fmt.Sprintf("scope %q is required", scope)})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rest-service/internal/auth"
+	"rest-service/internal/models"
+	"rest-service/internal/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func testToken(t *testing.T, userID uuid.UUID, roles ...string) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID.String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Roles: roles,
+	}).SignedString(testSecret)
+	require.NoError(t, err)
+	return "Bearer " + token
+}
+
+// newAuthRouter создаёт роутер API подписок, закрытый проверкой JWT
+func newAuthRouter(t *testing.T, repo repository.SubscriptionRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	authenticator, err := auth.NewAuthenticator(auth.Options{HMACSecret: testSecret})
+	require.NoError(t, err)
+
+	handler := NewSubscriptionHandler(repo, repository.NewMemoryExchangeRateRepository())
+	router := newTestRouter()
+	api := router.Group("/subscriptions", Authenticate(authenticator, nil))
+	api.POST("", handler.Create)
+	api.GET("", handler.GetAll)
+	api.GET("/:id", handler.GetByID)
+	api.PUT("/:id", handler.Update)
+	api.DELETE("/:id", handler.Delete)
+	api.GET("/sum", handler.GetSum)
+	return router
+}
+
+func TestAuthenticate_Unauthorized(t *testing.T) {
+	router := newAuthRouter(t, &MockSubscriptionRepository{})
+
+	for name, header := range map[string]string{
+		"missing":      "",
+		"wrong scheme": "Basic dXNlcjpwYXNz",
+		"garbage":      "Bearer not-a-jwt",
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/subscriptions/1", nil)
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+			var problem Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, ProblemUnauthorized, problem.Type)
+		})
+	}
+}
+
+func TestAuthenticate_Scoping(t *testing.T) {
+	owner, stranger := uuid.New(), uuid.New()
+	var deleted []int
+	var deletedVersion int
+	var filtered uuid.UUID
+	repo := &MockSubscriptionRepository{
+		GetByIDFunc: func(ctx context.Context, id int) (*models.Subscription, error) {
+			return &models.Subscription{ID: id, UserID: owner, ServiceName: "Yandex Plus", Price: 400, StartDate: "07-2025", Version: 3}, nil
+		},
+		UpdateFunc: func(ctx context.Context, id int, sub *models.Subscription, version int) error {
+			// Подписку успели передать другому пользователю
+			return repository.ErrVersionMismatch
+		},
+		GetAllFunc: func(ctx context.Context, filter repository.SubscriptionFilter) (*repository.SubscriptionPage, error) {
+			filtered = filter.UserID
+			return &repository.SubscriptionPage{}, nil
+		},
+		DeleteFunc: func(ctx context.Context, id int, version int) error {
+			deleted = append(deleted, id)
+			deletedVersion = version
+			return nil
+		},
+		GetCostsFunc: func(ctx context.Context, filter repository.CostFilter) ([]repository.MonthlyCost, error) {
+			if len(filter.UserIDs) != 1 || filter.UserIDs[0] != owner {
+				return nil, nil
+			}
+			return []repository.MonthlyCost{monthlyCost(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), "RUB", 40000, 40000)}, nil
+		},
+	}
+	router := newAuthRouter(t, repo)
+
+	do := func(method, target, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", token)
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	ownerToken := testToken(t, owner)
+	strangerToken := testToken(t, stranger)
+	adminToken := testToken(t, uuid.New(), auth.RoleAdmin)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/subscriptions/1", ownerToken, "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/subscriptions/1", strangerToken, "").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/subscriptions/1", adminToken, "").Code)
+
+	// Список без фильтра ограничивается своим пользователем, чужой фильтр запрещён
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/subscriptions", strangerToken, "").Code)
+	assert.Equal(t, stranger, filtered)
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/subscriptions?user_id="+owner.String(), strangerToken, "").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/subscriptions", adminToken, "").Code)
+	assert.Equal(t, uuid.Nil, filtered)
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/subscriptions/1", strangerToken, "").Code)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/subscriptions/1", ownerToken, "").Code)
+	assert.Equal(t, []int{1}, deleted)
+	// Без If-Match удаление всё равно условно по версии, на которой проверен владелец
+	assert.Equal(t, 3, deletedVersion)
+	body := `{"service_name":"Yandex Plus","price":400,"user_id":"` + owner.String() + `","start_date":"07-2025"}`
+	assert.Equal(t, http.StatusConflict, do(http.MethodPut, "/subscriptions/1", ownerToken, body).Code)
+
+	sum := "/subscriptions/sum?start=01-2025&end=12-2025&service_name=Yandex+Plus&user_id=" + owner.String()
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, sum, ownerToken, "").Code)
+	// Без user_id сумма считается по пользователю из токена
+	w := do(http.MethodGet, "/subscriptions/sum?start=01-2025&end=12-2025&service_name=Yandex+Plus", ownerToken, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"currency":"RUB","sum":400,"normalized_sum":400,"monthly_equivalent":33.33,
+		"by_currency":[{"currency":"RUB","sum":400,"normalized_sum":400,"converted_sum":400,"converted_normalized_sum":400}]}`, w.Body.String())
+	w = do(http.MethodGet, sum, strangerToken, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), ProblemForbidden)
+
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/subscriptions", strangerToken, body).Code)
+}
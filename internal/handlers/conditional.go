@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"rest-service/internal/models"
+	"rest-service/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var errPreconditionFailed = &httpError{status: http.StatusPreconditionFailed, detail: "subscription was modified, fetch it again and retry"}
+
+// errConcurrentModification — подписка изменилась между чтением и записью, которую сервер сделал условной сам, без If-Match
+var errConcurrentModification = &httpError{status: http.StatusConflict, detail: "subscription was modified concurrently, retry the request"}
+
+// ownedVersion возвращает версию для записи в подписку пользователя с токеном.
+// Без If-Match запись всё равно делается условной по прочитанной версии: иначе подписку,
+// которую между проверкой владельца и записью передали другому пользователю, изменил бы прежний владелец
+func ownedVersion(c *gin.Context, current *models.Subscription, version int) int {
+	if version == 0 && scopedUser(c) != uuid.Nil {
+		return current.Version
+	}
+	return version
+}
+
+// conditionalWriteError заменяет несовпадение версии на 409, если условие добавил сервер, а не клиент
+func conditionalWriteError(c *gin.Context, err error) error {
+	if errors.Is(err, repository.ErrVersionMismatch) && c.GetHeader("If-Match") == "" {
+		return errConcurrentModification
+	}
+	return err
+}
+
+// etag возвращает ETag версии подписки
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// setValidators задаёт ETag и Last-Modified подписки, по которым клиент делает условные запросы
+func setValidators(c *gin.Context, sub *models.Subscription) {
+	c.Header("ETag", etag(sub.Version))
+	setLastModified(c, sub.UpdatedAt)
+}
+
+func setLastModified(c *gin.Context, t time.Time) {
+	if !t.IsZero() {
+		c.Header("Last-Modified", t.UTC().Format(http.TimeFormat))
+	}
+}
+
+// notModified сообщает, что у клиента актуальная копия подписки: If-None-Match совпадает с ETag,
+// а если его нет — подписка не менялась после If-Modified-Since (RFC 9110, раздел 13.2.2)
+func notModified(c *gin.Context, sub *models.Subscription) bool {
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		return matchETag(inm, etag(sub.Version), true)
+	}
+	since, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// Last-Modified передаётся с точностью до секунды
+	return !sub.UpdatedAt.Truncate(time.Second).After(since)
+}
+
+// matchETag сообщает, перечислен ли tag в заголовке If-Match или If-None-Match.
+// weak включает слабое сравнение, при котором W/"1" совпадает с "1" (RFC 9110, раздел 8.8.3.2)
+func matchETag(header, tag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch проверяет If-Match по текущей версии подписки и возвращает версию,
+// при которой хранилище должно выполнить изменение. 0 — без проверки: заголовка нет или он равен "*"
+func checkIfMatch(c *gin.Context, current *models.Subscription) (int, error) {
+	header := c.GetHeader("If-Match")
+	if header == "" || strings.TrimSpace(header) == "*" {
+		return 0, nil
+	}
+	if !matchETag(header, etag(current.Version), false) {
+		return 0, errPreconditionFailed
+	}
+	return current.Version, nil
+}
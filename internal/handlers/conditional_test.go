@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"rest-service/internal/models"
+	"rest-service/internal/repository"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionHandler_Conditional(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := repository.NewMemorySubscriptionRepository()
+	userID := uuid.New()
+	_, err := repo.Create(context.Background(), &models.Subscription{ServiceName: "Netflix", Price: 500, UserID: userID, StartDate: "07-2025"})
+	require.NoError(t, err)
+
+	handler := NewSubscriptionHandler(repo, repository.NewMemoryExchangeRateRepository())
+	router := newTestRouter()
+	router.GET("/subscriptions/:id", handler.GetByID)
+	router.PUT("/subscriptions/:id", handler.Update)
+	router.PATCH("/subscriptions/:id", handler.Patch)
+	router.DELETE("/subscriptions/:id", handler.Delete)
+
+	do := func(method string, headers map[string]string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/subscriptions/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	put := `{"service_name": "Netflix Premium", "price": 900, "user_id": "` + userID.String() + `", "start_date": "07-2025"}`
+
+	w := do(http.MethodGet, nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+
+	w = do(http.MethodGet, map[string]string{"If-None-Match": `W/"1"`}, "")
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	lastModified := w.Header().Get("Last-Modified")
+	require.NotEmpty(t, lastModified)
+	assert.Equal(t, http.StatusNotModified, do(http.MethodGet, map[string]string{"If-Modified-Since": lastModified}, "").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, map[string]string{"If-Modified-Since": "Mon, 01 Jan 2024 00:00:00 GMT"}, "").Code)
+
+	// Второй редактор с устаревшим ETag не перезаписывает изменения первого
+	w = do(http.MethodPut, map[string]string{"If-Match": `"1"`}, put)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	w = do(http.MethodPut, map[string]string{"If-Match": `"1"`}, put)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Contains(t, w.Body.String(), ProblemPreconditionFailed)
+
+	w = do(http.MethodGet, map[string]string{"If-None-Match": `"1"`}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = do(http.MethodPatch, map[string]string{"If-Match": `"1", "5"`}, `{"price": 100}`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = do(http.MethodPatch, map[string]string{"If-Match": `"1", "2"`}, `{"price": 100}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+	assert.Contains(t, w.Body.String(), `"version":3`)
+
+	// Сравнение в If-Match строгое: слабый ETag не подходит
+	assert.Equal(t, http.StatusPreconditionFailed, do(http.MethodDelete, map[string]string{"If-Match": `W/"3"`}, "").Code)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, map[string]string{"If-Match": `"3"`}, "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, map[string]string{"If-Match": "*"}, "").Code)
+}
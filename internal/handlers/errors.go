@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"rest-service/internal/auth"
+	"rest-service/internal/logging"
+	"rest-service/internal/repository"
+	"rest-service/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// RequestIDHeader — заголовок с идентификатором запроса
+	RequestIDHeader = "X-Request-ID"
+
+	requestIDKey = "request_id"
+
+	problemContentType = "application/problem+json"
+)
+
+// Стабильные идентификаторы типов ошибок (RFC 7807, поле type)
+const (
+	ProblemBadRequest           = "/problems/bad-request"
+	ProblemValidation           = "/problems/validation-error"
+	ProblemUnauthorized         = "/problems/unauthorized"
+	ProblemForbidden            = "/problems/forbidden"
+	ProblemNotFound             = "/problems/not-found"
+	ProblemConflict             = "/problems/conflict"
+	ProblemPreconditionFailed   = "/problems/precondition-failed"
+	ProblemUnsupportedMediaType = "/problems/unsupported-media-type"
+	ProblemPayloadTooLarge      = "/problems/payload-too-large"
+	ProblemTooManyRequests      = "/problems/too-many-requests"
+	ProblemUnavailable          = "/problems/service-unavailable"
+	ProblemInternal             = "/problems/internal-error"
+)
+
+// Problem — тело ответа об ошибке в формате application/problem+json (RFC 7807)
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	Errors    validation.Errors `json:"errors,omitempty"`
+}
+
+// httpError — ошибка уровня обработчика, текст которой безопасно показывать клиенту
+type httpError struct {
+	status int
+	detail string
+}
+
+func (e *httpError) Error() string { return e.detail }
+
+func badRequest(detail string) error {
+	return &httpError{status: http.StatusBadRequest, detail: detail}
+}
+
+// RequestID берёт идентификатор запроса из заголовка X-Request-ID или генерирует новый,
+// возвращает его в ответе и кладёт в контекст запроса логгер с полем request_id
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = uuid.NewString()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		entry := log.WithField(requestIDKey, id)
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), entry))
+		c.Next()
+	}
+}
+
+// ErrorMiddleware превращает ошибки, добавленные обработчиками через c.Error,
+// в ответ application/problem+json. Подробности внутренних ошибок пишутся только в лог
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+		problem := problemFor(err)
+		problem.Instance = c.Request.URL.Path
+		problem.RequestID = c.GetString(requestIDKey)
+
+		entry := logging.FromContext(c.Request.Context()).WithField("status", problem.Status).WithError(err)
+		if problem.Status >= http.StatusInternalServerError {
+			entry.Error("Request failed")
+		} else {
+			entry.Debug("Request rejected")
+		}
+
+		switch problem.Status {
+		case http.StatusUnauthorized:
+			c.Header("WWW-Authenticate", `Bearer realm="rest-service"`)
+		case http.StatusServiceUnavailable:
+			c.Header("Retry-After", "5")
+		}
+		c.Header("Content-Type", problemContentType)
+		c.JSON(problem.Status, problem)
+	}
+}
+
+// NoRoute отвечает 404 в формате problem+json для неизвестных маршрутов
+func NoRoute(c *gin.Context) {
+	_ = c.Error(&httpError{status: http.StatusNotFound, detail: "route not found"})
+}
+
+func problemFor(err error) Problem {
+	var httpErr *httpError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &httpErr):
+		return Problem{Type: problemType(httpErr.status), Title: http.StatusText(httpErr.status), Status: httpErr.status, Detail: httpErr.detail}
+	case errors.As(err, &maxBytesErr):
+		return Problem{Type: ProblemPayloadTooLarge, Title: "Request Entity Too Large", Status: http.StatusRequestEntityTooLarge,
+			Detail: fmt.Sprintf("request body must not exceed %d bytes", maxBytesErr.Limit)}
+	case validation.FieldErrors(err) != nil:
+		return Problem{Type: ProblemValidation, Title: "Validation failed", Status: http.StatusUnprocessableEntity,
+			Detail: "one or more fields are invalid", Errors: validation.FieldErrors(err)}
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return Problem{Type: ProblemValidation, Title: "Validation failed", Status: http.StatusUnprocessableEntity,
+			Detail: "one or more fields are invalid",
+			Errors: validation.Errors{{Field: typeErr.Field, Message: "must be of type " + typeErr.Type.String()}}}
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return Problem{Type: ProblemUnauthorized, Title: "Unauthorized", Status: http.StatusUnauthorized, Detail: "missing or invalid credentials"}
+	case errors.Is(err, repository.ErrNotFound):
+		return Problem{Type: ProblemNotFound, Title: "Not Found", Status: http.StatusNotFound, Detail: "subscription not found"}
+	case errors.Is(err, repository.ErrVersionMismatch):
+		return Problem{Type: ProblemPreconditionFailed, Title: "Precondition Failed", Status: http.StatusPreconditionFailed, Detail: errPreconditionFailed.detail}
+	case errors.Is(err, repository.ErrConflict):
+		return Problem{Type: ProblemConflict, Title: "Conflict", Status: http.StatusConflict, Detail: "request conflicts with existing data"}
+	case errors.Is(err, repository.ErrValidation):
+		return Problem{Type: ProblemValidation, Title: "Validation failed", Status: http.StatusUnprocessableEntity, Detail: "subscription violates data constraints"}
+	case errors.Is(err, repository.ErrUnavailable):
+		return Problem{Type: ProblemUnavailable, Title: "Service Unavailable", Status: http.StatusServiceUnavailable, Detail: "storage is temporarily unavailable, retry later"}
+	default:
+		return Problem{Type: ProblemInternal, Title: "Internal Server Error", Status: http.StatusInternalServerError, Detail: "internal server error"}
+	}
+}
+
+func problemType(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return ProblemUnauthorized
+	case http.StatusForbidden:
+		return ProblemForbidden
+	case http.StatusNotFound:
+		return ProblemNotFound
+	case http.StatusConflict:
+		return ProblemConflict
+	case http.StatusPreconditionFailed:
+		return ProblemPreconditionFailed
+	case http.StatusRequestEntityTooLarge:
+		return ProblemPayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return ProblemUnsupportedMediaType
+	case http.StatusUnprocessableEntity:
+		return ProblemValidation
+	case http.StatusTooManyRequests:
+		return ProblemTooManyRequests
+	case http.StatusServiceUnavailable:
+		return ProblemUnavailable
+	case http.StatusInternalServerError:
+		return ProblemInternal
+	default:
+		return ProblemBadRequest
+	}
+}
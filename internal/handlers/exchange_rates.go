@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"rest-service/internal/exchange"
+	"rest-service/internal/repository"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errUnsupportedRatesType = &httpError{
+	status: http.StatusUnsupportedMediaType,
+	detail: "unsupported exchange rates content type, expected application/xml (CBR daily rates) or text/csv",
+}
+
+type ExchangeRateHandler struct {
+	repo repository.ExchangeRateRepository
+}
+
+func NewExchangeRateHandler(repo repository.ExchangeRateRepository) *ExchangeRateHandler {
+	return &ExchangeRateHandler{repo: repo}
+}
+
+// ratesFormat определяет формат загружаемых курсов по Content-Type
+func ratesFormat(contentType string) (exchange.Format, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/xml", "text/xml":
+		return exchange.FormatCBR, nil
+	case "text/csv":
+		return exchange.FormatCSV, nil
+	}
+	return "", errUnsupportedRatesType
+}
+
+// Import godoc
+// @Summary Import exchange rates
+// @Description Load exchange rates to RUB from the CBR daily rates XML (application/xml) or from CSV (text/csv) with the header currency,date,rate and an optional nominal column, dates in YYYY-MM-DD.
+// @Description A rate is effective from its date until the next rate of the same currency, rates already stored for the same currency and date are replaced. Requires the admin scope
+// @Tags exchange-rates
+// @Accept xml
+// @Accept text/csv
+// @Produce json
+// @Success 200 {object} map[string]int "number of imported rates"
+// @Failure 400 {object} Problem "malformed file"
+// @Failure 401 {object} Problem "missing or invalid credentials"
+// @Failure 403 {object} Problem "admin scope is required"
+// @Failure 413 {object} Problem "request body too large"
+// @Failure 415 {object} Problem "unsupported content type"
+// @Failure 429 {object} Problem "rate limit exceeded, see Retry-After"
+// @Failure 500 {object} Problem "internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /exchange-rates [post]
+func (h *ExchangeRateHandler) Import(c *gin.Context) {
+	ctx, endSpan := startSpan(c, "ExchangeRateHandler.Import")
+	defer endSpan()
+
+	format, err := ratesFormat(c.ContentType())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	rates, err := exchange.Parse(c.Request.Body, format)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if !errors.As(err, &maxBytesErr) {
+			err = badRequest(err.Error())
+		}
+		c.Error(err)
+		return
+	}
+	if err := h.repo.Upsert(ctx, rates); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"imported": len(rates)})
+}
+
+// List godoc
+// @Summary List exchange rates
+// @Description List stored exchange rates to RUB, ordered by currency and date
+// @Tags exchange-rates
+// @Produce json
+// @Param currency query string false "ISO 4217 currency, all currencies when omitted"
+// @Success 200 {array} models.ExchangeRate
+// @Failure 401 {object} Problem "missing or invalid credentials"
+// @Failure 403 {object} Problem "the API key lacks the required scope"
+// @Failure 429 {object} Problem "rate limit exceeded, see Retry-After"
+// @Failure 500 {object} Problem "internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /exchange-rates [get]
+func (h *ExchangeRateHandler) List(c *gin.Context) {
+	ctx, endSpan := startSpan(c, "ExchangeRateHandler.List")
+	defer endSpan()
+
+	rates, err := h.repo.List(ctx, strings.ToUpper(c.Query("currency")))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, rates)
+}
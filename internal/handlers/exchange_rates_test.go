@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rest-service/internal/models"
+	"rest-service/internal/repository"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExchangeRateHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewExchangeRateHandler(repository.NewMemoryExchangeRateRepository())
+	router := newTestRouter()
+	router.POST("/exchange-rates", handler.Import)
+	router.GET("/exchange-rates", handler.List)
+
+	post := func(contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/exchange-rates", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("text/csv", "currency,date,rate\nUSD,2025-01-01,100.5\nEUR,2025-01-01,105\n")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"imported": 2}`, w.Body.String())
+
+	cbr := `<?xml version="1.0" encoding="UTF-8"?>
+<ValCurs Date="01.02.2025" name="Foreign Currency Market">
+	<Valute ID="R01235"><NumCode>840</NumCode><CharCode>USD</CharCode><Nominal>1</Nominal><Name>Доллар США</Name><Value>98,2500</Value></Valute>
+</ValCurs>`
+	w = post("application/xml; charset=utf-8", cbr)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"imported": 1}`, w.Body.String())
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, post("application/json", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post("text/csv", "currency,date,rate\nUSD,01.01.2025,100\n").Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/exchange-rates?currency=usd", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var rates []models.ExchangeRate
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rates))
+	require.Len(t, rates, 2)
+	assert.Equal(t, 100.5, rates[0].Rate)
+	assert.Equal(t, 98.25, rates[1].Rate)
+}
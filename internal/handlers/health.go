@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"rest-service/internal/health"
+	"rest-service/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Liveness godoc
+// @Summary Liveness probe
+// @Description Returns 200 while the process is running. Does not check dependencies
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.CheckResult
+// @Router /healthz [get]
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, health.CheckResult{Status: health.StatusOK})
+}
+
+// Readiness godoc
+// @Summary Readiness probe
+// @Description Checks that the database is reachable, migrations are applied and the server is not shutting down.
+// @Description Only the status of every check is returned, the reasons of failures are logged
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report "one or more checks failed"
+// @Router /readyz [get]
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	report := h.checker.Readiness(c.Request.Context())
+	if !report.Ready() {
+		logging.FromContext(c.Request.Context()).WithField("checks", report.Errors()).Warn("Readiness check failed")
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"rest-service/internal/health"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbErr := error(nil)
+	checker := health.NewChecker(time.Second)
+	checker.Add("database", func(ctx context.Context) error { return dbErr })
+
+	handler := NewHealthHandler(checker)
+	router := newTestRouter()
+	router.GET("/healthz", handler.Liveness)
+	router.GET("/readyz", handler.Readiness)
+
+	get := func(path string) (*httptest.ResponseRecorder, health.Report) {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var report health.Report
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return w, report
+	}
+
+	w, report := get("/readyz")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, health.StatusOK, report.Checks["database"].Status)
+
+	dbErr = errors.New("connection refused")
+	w, report = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, health.StatusFail, report.Status)
+	assert.Equal(t, health.StatusFail, report.Checks["database"].Status)
+	assert.NotContains(t, w.Body.String(), "connection refused")
+
+	// Liveness не зависит от состояния БД
+	w, report = get("/healthz")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, health.StatusOK, report.Status)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"rest-service/internal/auth"
+	"rest-service/internal/logging"
+	"rest-service/internal/repository"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Заголовки идемпотентных запросов
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed" // true, если ответ повторён из сохранённого
+)
+
+const maxIdempotencyKeyLength = 255
+
+// idempotencyWriter запоминает тело ответа, чтобы сохранить его для повторов
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency делает запрос с заголовком Idempotency-Key безопасным для повтора.
+// Успешный ответ сохраняется на ttl, и повтор с тем же ключом и телом получает его без повторного выполнения.
+// Повтор с другим телом получает 422, а повтор, пока исходный запрос ещё выполняется, — 409.
+// Если запрос завершился ошибкой, ключ освобождается и запрос можно повторить.
+// Ключи разных пользователей и API-ключей не пересекаются. Должен стоять после Authenticate
+func Idempotency(repo repository.IdempotencyRepository, ttl, lockTimeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			_ = c.Error(badRequest("Idempotency-Key must not exceed 255 characters"))
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if !errors.As(err, &maxBytesErr) {
+				err = badRequest("cannot read request body")
+			}
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		owner := "anonymous"
+		if p, ok := auth.PrincipalFromContext(ctx); ok {
+			owner = p.ID()
+		}
+		key = owner + ":" + key
+		hash := sha256.Sum256([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n" + string(body)))
+		requestHash := hex.EncodeToString(hash[:])
+
+		now := time.Now()
+		rec, created, err := repo.Reserve(ctx, key, requestHash, now, now.Add(lockTimeout))
+		if err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		if !created {
+			switch {
+			case rec.RequestHash != requestHash:
+				_ = c.Error(&httpError{status: http.StatusUnprocessableEntity, detail: "Idempotency-Key was already used with a different request"})
+			case rec.Status == 0:
+				c.Header("Retry-After", "1")
+				_ = c.Error(&httpError{status: http.StatusConflict, detail: "a request with this Idempotency-Key is still in progress"})
+			default:
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(rec.Status, "application/json; charset=utf-8", rec.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		w := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		completed := false
+		defer func() {
+			c.Writer = w.ResponseWriter
+			// Ответ сохраняется и при отмене запроса клиентом: создание уже выполнено
+			storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+			// Если блокировка истекла и ключ занял повтор, токен не совпадёт и чужая запись не будет затронута
+			if completed {
+				err = repo.Complete(storeCtx, key, rec.Token, w.Status(), w.body.Bytes(), time.Now().Add(ttl))
+			} else {
+				err = repo.Release(storeCtx, key, rec.Token)
+			}
+			switch {
+			case errors.Is(err, repository.ErrNotFound):
+				logging.FromContext(ctx).Warn("Idempotency key lock expired before the request finished")
+			case err != nil:
+				logging.FromContext(ctx).WithError(err).Error("Failed to store idempotency key")
+			}
+		}()
+
+		c.Next()
+
+		// Ошибки отрисовывает ErrorMiddleware позже, поэтому неуспех виден по c.Errors
+		status := w.Status()
+		completed = len(c.Errors) == 0 && status >= 200 && status < 300
+	}
+}
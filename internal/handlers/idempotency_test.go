@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"rest-service/internal/auth"
+	"rest-service/internal/models"
+	"rest-service/internal/repository"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var created atomic.Int32
+	handler := NewSubscriptionHandler(&MockSubscriptionRepository{
+		CreateFunc: func(ctx context.Context, sub *models.Subscription) (int, error) {
+			return int(created.Add(1)), nil
+		},
+	}, repository.NewMemoryExchangeRateRepository())
+	router := newTestRouter()
+	router.Use(func(c *gin.Context) {
+		// Вместо Authenticate: пользователь берётся из заголовка
+		if id := c.GetHeader("X-Test-User"); id != "" {
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), auth.Principal{UserID: uuid.MustParse(id)}))
+		}
+	})
+	router.POST("/subscriptions", Idempotency(repository.NewMemoryIdempotencyRepository(), time.Hour, time.Minute), handler.Create)
+
+	alice, bob := uuid.NewString(), uuid.NewString()
+	valid := `{"service_name": "Netflix", "price": 500, "user_id": "` + alice + `", "start_date": "07-2025"}`
+	post := func(key, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/subscriptions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("k1", alice, valid)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"id": 1}`, w.Body.String())
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+
+	// Повтор получает исходный ответ, подписка не создаётся повторно
+	w = post("k1", alice, valid)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"id": 1}`, w.Body.String())
+	assert.Equal(t, "true", w.Header().Get(IdempotentReplayedHeader))
+	assert.EqualValues(t, 1, created.Load())
+
+	w = post("k1", alice, strings.Replace(valid, "500", "600", 1))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "different request")
+
+	// Ключи разных клиентов не пересекаются
+	w = post("k1", bob, strings.Replace(valid, alice, bob, 1))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"id": 2}`, w.Body.String())
+
+	// Неуспешный запрос не занимает ключ
+	w = post("k2", alice, `{"service_name": ""}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = post("k2", alice, valid)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"id": 3}`, w.Body.String())
+
+	w = post("", alice, valid)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"id": 4}`, w.Body.String())
+
+	w = post(strings.Repeat("x", 256), alice, valid)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestIdempotency_InProgress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	started, release := make(chan struct{}), make(chan struct{})
+	router := newTestRouter()
+	router.POST("/subscriptions", Idempotency(repository.NewMemoryIdempotencyRepository(), time.Hour, time.Minute), func(c *gin.Context) {
+		close(started)
+		<-release
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/subscriptions", strings.NewReader(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "k")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- post() }()
+	<-started
+
+	w := post()
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	close(release)
+	assert.Equal(t, http.StatusCreated, (<-first).Code)
+	w = post()
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "true", w.Header().Get(IdempotentReplayedHeader))
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"rest-service/internal/models"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json" // RFC 7396
+	jsonPatchContentType  = "application/json-patch+json"  // RFC 6902
+)
+
+var errUnsupportedPatchType = &httpError{
+	status: http.StatusUnsupportedMediaType,
+	detail: "unsupported patch content type, expected " + mergePatchContentType + " or " + jsonPatchContentType,
+}
+
+// applyPatch применяет тело PATCH-запроса к текущему состоянию подписки и возвращает результат.
+// Тип патча определяется по Content-Type; обычный application/json трактуется как JSON Merge Patch.
+// Ошибки возвращаются в виде, пригодном для ответа клиенту
+func applyPatch(current models.Subscription, contentType string, body []byte) (models.Subscription, error) {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return current, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case mergePatchContentType, "application/json", "":
+		doc, err = jsonpatch.MergePatch(doc, body)
+	case jsonPatchContentType:
+		var patch jsonpatch.Patch
+		patch, err = jsonpatch.DecodePatch(body)
+		if err == nil {
+			doc, err = patch.Apply(doc)
+		}
+	default:
+		return current, errUnsupportedPatchType
+	}
+	if err != nil {
+		return current, badRequest("cannot apply patch: " + err.Error())
+	}
+
+	var patched models.Subscription
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&patched); err != nil {
+		return current, bindError(err)
+	}
+	// Удалённые из документа условия оплаты возвращаются к значениям по умолчанию
+	patched.SetDefaults()
+	switch {
+	case patched.ID != current.ID:
+		return current, badRequest("id cannot be changed")
+	case patched.Version != current.Version:
+		return current, badRequest("version cannot be changed, use If-Match")
+	case !patched.CreatedAt.Equal(current.CreatedAt), !patched.UpdatedAt.Equal(current.UpdatedAt):
+		return current, badRequest("created_at and updated_at cannot be changed")
+	}
+	return patched, nil
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"rest-service/internal/auth"
+	"rest-service/internal/logging"
+	"rest-service/internal/ratelimit"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Заголовки ответа с состоянием лимита запросов
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset" // секунд до полного восстановления бюджета
+)
+
+// rateLimitRemainingKey — ключ gin-контекста с остатком самого строгого из пройденных лимитов
+const rateLimitRemainingKey = "ratelimit_remaining"
+
+// RateKey определяет, из чьего бюджета расходуется запрос
+type RateKey func(c *gin.Context) string
+
+// ByIP расходует бюджет IP-адреса клиента
+func ByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// ByClient расходует бюджет пользователя или API-ключа, а для анонимных запросов — IP-адреса.
+// Должен стоять после Authenticate
+func ByClient(c *gin.Context) string {
+	if p, ok := auth.PrincipalFromContext(c.Request.Context()); ok {
+		return p.ID()
+	}
+	return ByIP(c)
+}
+
+// RateLimit списывает токен из бакета name для клиента key(c) и отвечает 429, если бюджет исчерпан.
+// Несколько RateLimit на одном маршруте ведут независимые бакеты, а в X-RateLimit-* попадает самый строгий.
+// Если хранилище лимитов недоступно, запрос пропускается: отказ хранилища не должен останавливать API
+func RateLimit(store ratelimit.Store, name string, limit ratelimit.Limit, key RateKey) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res, err := store.Take(c.Request.Context(), name+":"+key(c), limit)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).WithError(err).WithField("limit", name).Warn("Rate limit store failed, letting the request through")
+			c.Next()
+			return
+		}
+
+		if prev, ok := c.Get(rateLimitRemainingKey); !ok || !res.Allowed || res.Remaining < prev.(int) {
+			c.Set(rateLimitRemainingKey, res.Remaining)
+			c.Header(RateLimitLimitHeader, strconv.Itoa(res.Limit))
+			c.Header(RateLimitRemainingHeader, strconv.Itoa(res.Remaining))
+			c.Header(RateLimitResetHeader, strconv.Itoa(ceilSeconds(res.Reset)))
+		}
+		if !res.Allowed {
+			retryAfter := max(ceilSeconds(res.RetryAfter), 1)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			_ = c.Error(&httpError{status: http.StatusTooManyRequests, detail: fmt.Sprintf("rate limit exceeded, retry in %d s", retryAfter)})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
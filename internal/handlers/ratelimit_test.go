@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"rest-service/internal/auth"
+	"rest-service/internal/ratelimit"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store is down")
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := ratelimit.NewMemoryStore()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	router := newTestRouter()
+	router.Use(func(c *gin.Context) {
+		// Вместо Authenticate: пользователь берётся из заголовка
+		if id := c.GetHeader("X-Test-User"); id != "" {
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), auth.Principal{UserID: uuid.MustParse(id)}))
+		}
+	})
+	router.Use(RateLimit(store, "client", ratelimit.Limit{Rate: 0.1, Burst: 3}, ByClient))
+	router.GET("/cheap", ok)
+	router.GET("/sum", RateLimit(store, "sum", ratelimit.Limit{Rate: 0.1, Burst: 1}, ByClient), ok)
+	router.GET("/open", RateLimit(failingStore{}, "broken", ratelimit.Limit{Rate: 1, Burst: 1}, ByIP), ok)
+
+	get := func(path, user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if user != "" {
+			req.Header.Set("X-Test-User", user)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	alice, bob := uuid.NewString(), uuid.NewString()
+
+	w := get("/cheap", alice)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "3", w.Header().Get(RateLimitLimitHeader))
+	assert.Equal(t, "2", w.Header().Get(RateLimitRemainingHeader))
+	assert.Equal(t, "10", w.Header().Get(RateLimitResetHeader))
+
+	// В заголовках — самый строгий из двух лимитов маршрута
+	w = get("/sum", alice)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get(RateLimitLimitHeader))
+	assert.Equal(t, "0", w.Header().Get(RateLimitRemainingHeader))
+
+	w = get("/sum", alice)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), ProblemTooManyRequests)
+
+	// Бюджет общего лимита исчерпан, у другого пользователя свой бюджет
+	assert.Equal(t, http.StatusTooManyRequests, get("/cheap", alice).Code)
+	assert.Equal(t, http.StatusOK, get("/cheap", bob).Code)
+	assert.Equal(t, http.StatusOK, get("/cheap", "").Code)
+
+	assert.Equal(t, http.StatusOK, get("/open", "").Code)
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"rest-service/internal/models"
+	"rest-service/internal/repository"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// groupByMonth — разбивка отчёта по месяцам периода, в отличие от сервисов и пользователей она строится из помесячной стоимости
+const groupByMonth = "month"
+
+// ReportResponse — стоимость подписок за период в валюте currency с разбивкой по group_by
+type ReportResponse struct {
+	Currency      string        `json:"currency" example:"RUB"`
+	GroupBy       string        `json:"group_by,omitempty" example:"service_name"`
+	Sum           models.Amount `json:"sum" swaggertype:"number" example:"1299.5"`
+	NormalizedSum models.Amount `json:"normalized_sum" swaggertype:"number" example:"1299.5"`
+	Count         int           `json:"count" example:"3"` // число подписок, активных хотя бы в одном месяце периода
+	Groups        []ReportGroup `json:"groups"`            // пустой без group_by
+}
+
+// ReportGroup — стоимость подписок одной группы отчёта
+type ReportGroup struct {
+	Key           string        `json:"key" example:"Netflix"` // имя сервиса, UUID пользователя или месяц MM-YYYY
+	Sum           models.Amount `json:"sum" swaggertype:"number" example:"399.99"`
+	NormalizedSum models.Amount `json:"normalized_sum" swaggertype:"number" example:"399.99"`
+	Count         int           `json:"count" example:"1"`      // подписки группы, активные в периоде, а при group_by=month — в этом месяце
+	Share         float64       `json:"share" example:"0.3077"` // доля sum группы в общей sum, от 0 до 1
+}
+
+// costScope разбирает повторяемые query-параметры user_id и service_name.
+// Пользователю с токеном без user_id подставляется он сам, а чужие user_id запрещены
+func costScope(c *gin.Context) (userIDs []uuid.UUID, serviceNames []string, err error) {
+	for _, s := range c.QueryArray("user_id") {
+		if s == "" {
+			continue
+		}
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, nil, badRequest("invalid user_id")
+		}
+		if err := checkUser(c, id); err != nil {
+			return nil, nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	if scope := scopedUser(c); len(userIDs) == 0 && scope != uuid.Nil {
+		userIDs = []uuid.UUID{scope}
+	}
+	for _, name := range c.QueryArray("service_name") {
+		if name != "" {
+			serviceNames = append(serviceNames, name)
+		}
+	}
+	return userIDs, serviceNames, nil
+}
+
+// GetReport godoc
+// @Summary Get cost of subscriptions grouped by service, user or month
+// @Description Get cost of subscriptions over the period [start, end] in the requested currency, filtered by any number of users and services and grouped by group_by.
+// @Description sum and normalized_sum have the same meaning as in /subscriptions/sum, count is the number of subscriptions active in at least one month of the period, share is the part of the total sum that falls on the group.
+// @Description Groups by service or user are ordered by sum descending, groups by month are in calendar order. A user identified by the bearer token only sees their own subscriptions
+// @Tags subscriptions
+// @Produce json
+// @Param start query string true "Start date in MM-YYYY"
+// @Param end query string true "End date in MM-YYYY"
+// @Param user_id query []string false "User UUIDs, all users when omitted" collectionFormat(multi)
+// @Param service_name query []string false "Service names, all services when omitted" collectionFormat(multi)
+// @Param group_by query string false "Grouping of the totals, no groups when omitted" Enums(service_name, user_id, month)
+// @Param currency query string false "ISO 4217 currency of the totals, RUB by default"
+// @Success 200 {object} ReportResponse
+// @Failure 400 {object} Problem "missing or invalid parameters, or a period longer than 120 months"
+// @Failure 401 {object} Problem "missing or invalid credentials"
+// @Failure 403 {object} Problem "user_id belongs to another user, or the API key lacks the required scope"
+// @Failure 422 {object} Problem "no exchange rate for a currency in one of the months"
+// @Failure 429 {object} Problem "rate limit exceeded, see Retry-After"
+// @Failure 500 {object} Problem "internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /subscriptions/report [get]
+func (h *SubscriptionHandler) GetReport(c *gin.Context) {
+	ctx, endSpan := startSpan(c, "SubscriptionHandler.GetReport")
+	defer endSpan()
+
+	start := c.Query("start")
+	end := c.Query("end")
+	if start == "" || end == "" {
+		c.Error(badRequest("start and end params are required"))
+		return
+	}
+	if _, _, err := parsePeriod(start, end); err != nil {
+		c.Error(err)
+		return
+	}
+	currency, err := parseCurrency(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	groupBy := c.Query("group_by")
+	filter := repository.CostFilter{Start: start, End: end}
+	if groupBy != groupByMonth {
+		filter.GroupBy = repository.CostGroup(groupBy)
+		if !filter.GroupBy.Valid() {
+			c.Error(badRequest("invalid group_by, expected service_name, user_id or month"))
+			return
+		}
+	}
+	if filter.UserIDs, filter.ServiceNames, err = costScope(c); err != nil {
+		c.Error(err)
+		return
+	}
+
+	costs, err := h.repo.GetCosts(ctx, filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	counts, err := h.repo.CountSubscriptions(ctx, filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	table, err := h.rateTable(ctx, costs, currency)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Ключ группы — сервис или пользователь из GetCosts, а при разбивке по месяцам — сам месяц
+	type converted struct{ charged, normalized float64 }
+	totals := make(map[string]*converted)
+	groupCounts := make(map[string]int)
+	var keys []string
+	for _, cost := range costs {
+		key := cost.Group
+		if groupBy == groupByMonth {
+			key = cost.Month.Format(models.MonthLayout)
+			groupCounts[key] += cost.Active
+		}
+		charged, err := table.Convert(cost.Charged.Amount.Float64(), cost.Charged.Currency, currency, cost.Month)
+		if err != nil {
+			c.Error(rateError(err))
+			return
+		}
+		normalized, err := table.Convert(cost.Normalized.Amount.Float64(), cost.Normalized.Currency, currency, cost.Month)
+		if err != nil {
+			c.Error(rateError(err))
+			return
+		}
+		total, ok := totals[key]
+		if !ok {
+			total = &converted{}
+			totals[key] = total
+			keys = append(keys, key)
+		}
+		total.charged += charged
+		total.normalized += normalized
+	}
+
+	resp := ReportResponse{Currency: currency, GroupBy: groupBy, Groups: []ReportGroup{}}
+	for _, count := range counts {
+		resp.Count += count.Count
+		if groupBy != groupByMonth {
+			groupCounts[count.Group] = count.Count
+		}
+	}
+	groups := make([]ReportGroup, 0, len(keys))
+	for _, key := range keys {
+		group := ReportGroup{
+			Key:           key,
+			Sum:           models.AmountFromFloat(totals[key].charged),
+			NormalizedSum: models.AmountFromFloat(totals[key].normalized),
+			Count:         groupCounts[key],
+		}
+		resp.Sum += group.Sum
+		resp.NormalizedSum += group.NormalizedSum
+		groups = append(groups, group)
+	}
+	if groupBy == "" {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	for i := range groups {
+		if resp.Sum != 0 {
+			groups[i].Share = math.Round(float64(groups[i].Sum)/float64(resp.Sum)*10000) / 10000
+		}
+	}
+	// Месяцы уже идут по порядку, как их вернул GetCosts
+	if groupBy != groupByMonth {
+		sort.SliceStable(groups, func(i, j int) bool {
+			if groups[i].Sum != groups[j].Sum {
+				return groups[i].Sum > groups[j].Sum
+			}
+			return groups[i].Key < groups[j].Key
+		})
+	}
+	resp.Groups = groups
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rest-service/internal/models"
+	"rest-service/internal/repository"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionHandler_GetReport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	repo := repository.NewMemorySubscriptionRepository()
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+	end := "02-2025"
+	repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: 50000, UserID: alice, StartDate: "01-2025", EndDate: &end})
+	repo.Create(ctx, &models.Subscription{ServiceName: "Spotify", Price: 20000, UserID: alice, StartDate: "02-2025"})
+	repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: 70000, UserID: bob, StartDate: "03-2025"})
+	repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: 99900, UserID: carol, StartDate: "01-2025"})
+
+	handler := NewSubscriptionHandler(repo, repository.NewMemoryExchangeRateRepository())
+	router := newTestRouter()
+	router.GET("/subscriptions/report", handler.GetReport)
+
+	get := func(query string) (*httptest.ResponseRecorder, ReportResponse) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/subscriptions/report?"+query, nil))
+		var resp ReportResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w, resp
+	}
+
+	// Траты по сервисам у двух пользователей: Netflix 2 × 500 + 700, Spotify 2 × 200
+	w, resp := get("start=01-2025&end=03-2025&user_id=" + alice.String() + "&user_id=" + bob.String() + "&group_by=service_name")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, ReportResponse{
+		Currency: "RUB", GroupBy: "service_name", Sum: 210000, NormalizedSum: 210000, Count: 3,
+		Groups: []ReportGroup{
+			{Key: "Netflix", Sum: 170000, NormalizedSum: 170000, Count: 2, Share: 0.8095},
+			{Key: "Spotify", Sum: 40000, NormalizedSum: 40000, Count: 1, Share: 0.1905},
+		},
+	}, resp)
+
+	// Траты по пользователям на Netflix
+	w, resp = get("start=01-2025&end=03-2025&service_name=Netflix&group_by=user_id")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, resp.Groups, 3)
+	assert.Equal(t, ReportGroup{Key: carol.String(), Sum: 299700, NormalizedSum: 299700, Count: 1, Share: 0.6381}, resp.Groups[0])
+	assert.Equal(t, 3, resp.Count)
+
+	// По месяцам число подписок считается в каждом месяце
+	w, resp = get("start=01-2025&end=03-2025&user_id=" + alice.String() + "&group_by=month")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []ReportGroup{
+		{Key: "01-2025", Sum: 50000, NormalizedSum: 50000, Count: 1, Share: 0.3571},
+		{Key: "02-2025", Sum: 70000, NormalizedSum: 70000, Count: 2, Share: 0.5},
+		{Key: "03-2025", Sum: 20000, NormalizedSum: 20000, Count: 1, Share: 0.1429},
+	}, resp.Groups)
+	assert.Equal(t, 2, resp.Count)
+
+	// Без group_by — только итоги
+	w, resp = get("start=01-2025&end=01-2025")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, ReportResponse{Currency: "RUB", Sum: 149900, NormalizedSum: 149900, Count: 2, Groups: []ReportGroup{}}, resp)
+
+	for _, query := range []string{
+		"end=01-2025",
+		"start=01-2025&end=01-2025&group_by=currency",
+		"start=01-2025&end=01-2025&user_id=" + alice.String() + "&user_id=42",
+	} {
+		w, _ := get(query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
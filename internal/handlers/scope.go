@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"rest-service/internal/auth"
+	"rest-service/internal/models"
+	"rest-service/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var errForeignUser = &httpError{status: http.StatusForbidden, detail: "access to another user's subscriptions is forbidden"}
+
+// scopedUser возвращает пользователя, данными которого ограничен запрос.
+// uuid.Nil — без ограничений: аутентификация выключена, у пользователя роль admin
+// или запрос выполняет сервис по API-ключу (его права ограничены скоупами)
+func scopedUser(c *gin.Context) uuid.UUID {
+	p, ok := auth.PrincipalFromContext(c.Request.Context())
+	if !ok || p.IsAdmin() || p.IsService() {
+		return uuid.Nil
+	}
+	return p.UserID
+}
+
+// checkUser запрещает пользователю, ограниченному своими данными, обращаться к данным userID другого пользователя
+func checkUser(c *gin.Context, userID uuid.UUID) error {
+	if scope := scopedUser(c); scope != uuid.Nil && userID != scope {
+		return errForeignUser
+	}
+	return nil
+}
+
+// getOwned возвращает подписку, если она доступна пользователю запроса.
+// Чужая подписка выглядит как несуществующая, чтобы не раскрывать чужие ID
+func (h *SubscriptionHandler) getOwned(ctx context.Context, c *gin.Context, id int) (*models.Subscription, error) {
+	sub, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if scope := scopedUser(c); scope != uuid.Nil && sub.UserID != scope {
+		return nil, repository.ErrNotFound
+	}
+	return sub, nil
+}
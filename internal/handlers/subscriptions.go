@@ -1,100 +1,269 @@
 package handlers
 
 import (
-	"log"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"net/http"
+	"rest-service/internal/exchange"
 	"rest-service/internal/models"
 	"rest-service/internal/repository"
+	"rest-service/internal/validation"
+	"slices"
+	"sort"
 	"strconv"
-
-	"database/sql"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type SubscriptionHandler struct {
-	repo repository.SubscriptionRepository
+	repo  repository.SubscriptionRepository
+	rates repository.ExchangeRateRepository
+}
+
+func NewSubscriptionHandler(repo repository.SubscriptionRepository, rates repository.ExchangeRateRepository) *SubscriptionHandler {
+	validation.Register()
+	return &SubscriptionHandler{repo: repo, rates: rates}
 }
 
-func NewSubscriptionHandler(repo repository.SubscriptionRepository) *SubscriptionHandler {
-	return &SubscriptionHandler{repo: repo}
+// bindError оставляет ошибки валидации полей (ответ 422) и превышения размера тела (413) как есть,
+// а остальные ошибки разбора тела запроса превращает в 400
+func bindError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+	if validation.FieldErrors(err) != nil || (errors.As(err, &typeErr) && typeErr.Field != "") || errors.As(err, &maxBytesErr) {
+		return err
+	}
+	if errors.Is(err, models.ErrAmountFormat) {
+		return badRequest(err.Error())
+	}
+	return badRequest("malformed JSON body")
 }
 
 // Create godoc
 // @Summary Create a new subscription
-// @Description Create a new subscription with JSON body
+// @Description Create a new subscription with JSON body. With an Idempotency-Key header a retry with the same key and body returns the original response instead of creating a duplicate. The price is charged once per billing cycle of billing_interval billing_periods, monthly by default
 // @Tags subscriptions
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Client-generated unique key of the request, up to 255 characters"
 // @Param subscription body models.Subscription true "Subscription data"
 // @Success 201 {object} map[string]int "id of created subscription"
-// @Failure 400 {object} map[string]string "invalid input"
-// @Failure 500 {object} map[string]string "internal server error"
+// @Header 201 {string} Idempotent-Replayed "true when the response is replayed for a repeated Idempotency-Key"
+// @Failure 400 {object} Problem "malformed JSON or too long Idempotency-Key"
+// @Failure 401 {object} Problem "missing or invalid credentials"
+// @Failure 403 {object} Problem "user_id belongs to another user, or the API key lacks the required scope"
+// @Failure 409 {object} Problem "a request with the same Idempotency-Key is still in progress, see Retry-After"
+// @Failure 413 {object} Problem "request body too large"
+// @Failure 422 {object} Problem "validation failed, per-field errors in fields, or Idempotency-Key was used with a different body"
+// @Failure 429 {object} Problem "rate limit exceeded, see Retry-After"
+// @Failure 500 {object} Problem "internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /subscriptions [post]
 func (h *SubscriptionHandler) Create(c *gin.Context) {
+	ctx, endSpan := startSpan(c, "SubscriptionHandler.Create")
+	defer endSpan()
+
 	var sub models.Subscription
 	if err := c.ShouldBindJSON(&sub); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(bindError(err))
+		return
+	}
+	if err := checkUser(c, sub.UserID); err != nil {
+		c.Error(err)
 		return
 	}
-	id, err := h.repo.Create(c.Request.Context(), &sub)
+	id, err := h.repo.Create(ctx, &sub)
 	if err != nil {
-		log.Printf("Error creating subscription: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"id": id})
 }
 
 // GetAll godoc
-// @Summary Get all subscriptions
-// @Description Retrieve list of all subscriptions
+// @Summary List subscriptions
+// @Description Retrieve a page of subscriptions with filtering and sorting. Total count is returned in the X-Total-Count header, the next page (keyset cursor) in the Link header with rel="next"
 // @Tags subscriptions
 // @Produce json
+// @Param user_id query string false "User UUID"
+// @Param service_name query string false "Exact service name"
+// @Param service_name_prefix query string false "Service name prefix"
+// @Param min_price query number false "Minimum price, up to 2 decimal places"
+// @Param max_price query number false "Maximum price, up to 2 decimal places"
+// @Param active_in query string false "Month in MM-YYYY when the subscription is active"
+// @Param sort query string false "Sort column" Enums(id, service_name, price, user_id, start_date, end_date)
+// @Param order query string false "Sort order" Enums(asc, desc)
+// @Param limit query int false "Page size (default 100, max 1000)"
+// @Param offset query int false "Number of subscriptions to skip (ignored when cursor is set)"
+// @Param cursor query string false "Opaque cursor of the next page"
+// @Param created_since query string false "Only subscriptions created at or after this time, RFC 3339"
+// @Param updated_since query string false "Only subscriptions changed at or after this time, RFC 3339. For incremental sync pass the largest updated_at seen so far"
 // @Success 200 {array} models.Subscription
-// @Failure 500 {object} map[string]string "internal server error"
+// @Header 200 {integer} X-Total-Count "Total number of matching subscriptions"
+// @Header 200 {string} Link "Link to the next page"
+// @Header 200 {string} Last-Modified "Latest updated_at on the page"
+// @Failure 400 {object} Problem "invalid query parameters"
+// @Failure 401 {object} Problem "missing or invalid credentials"
+// @Failure 403 {object} Problem "user_id filter belongs to another user, or the API key lacks the required scope"
+// @Failure 429 {object} Problem "rate limit exceeded, see Retry-After"
+// @Failure 500 {object} Problem "internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /subscriptions [get]
 func (h *SubscriptionHandler) GetAll(c *gin.Context) {
-	subs, err := h.repo.GetAll(c.Request.Context())
+	ctx, endSpan := startSpan(c, "SubscriptionHandler.GetAll")
+	defer endSpan()
+
+	filter, err := parseListFilter(c)
+	if err == nil {
+		err = filter.Normalize()
+	}
 	if err != nil {
-		log.Printf("Error fetching all subscriptions: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(badRequest(err.Error()))
 		return
 	}
-	c.JSON(http.StatusOK, subs)
+	if scope := scopedUser(c); scope != uuid.Nil {
+		if filter.UserID != uuid.Nil && filter.UserID != scope {
+			c.Error(errForeignUser)
+			return
+		}
+		filter.UserID = scope
+	}
+
+	page, err := h.repo.GetAll(ctx, filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(page.Total))
+	var lastModified time.Time
+	for _, sub := range page.Items {
+		if sub.UpdatedAt.After(lastModified) {
+			lastModified = sub.UpdatedAt
+		}
+	}
+	setLastModified(c, lastModified)
+	if page.NextCursor != "" {
+		next := *c.Request.URL
+		q := next.Query()
+		q.Del("offset")
+		q.Set("cursor", page.NextCursor)
+		next.RawQuery = q.Encode()
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+	}
+	c.JSON(http.StatusOK, page.Items)
+}
+
+// parseListFilter разбирает query-параметры списка подписок
+func parseListFilter(c *gin.Context) (repository.SubscriptionFilter, error) {
+	filter := repository.SubscriptionFilter{
+		ServiceName:       c.Query("service_name"),
+		ServiceNamePrefix: c.Query("service_name_prefix"),
+		ActiveIn:          c.Query("active_in"),
+		Sort:              c.Query("sort"),
+		Order:             c.Query("order"),
+		Cursor:            c.Query("cursor"),
+	}
+
+	if v := c.Query("user_id"); v != "" {
+		userID, err := uuid.Parse(v)
+		if err != nil {
+			return filter, errors.New("invalid user_id")
+		}
+		filter.UserID = userID
+	}
+
+	times := []struct {
+		name string
+		dst  *time.Time
+	}{{"created_since", &filter.CreatedSince}, {"updated_since", &filter.UpdatedSince}}
+	for _, p := range times {
+		if v := c.Query(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s, expected RFC 3339 time", p.name)
+			}
+			*p.dst = t
+		}
+	}
+
+	ints := []struct {
+		name string
+		dst  *int
+	}{{"limit", &filter.Limit}, {"offset", &filter.Offset}}
+	for _, p := range ints {
+		if v := c.Query(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s", p.name)
+			}
+			*p.dst = n
+		}
+	}
+
+	prices := []struct {
+		name string
+		dst  **models.Amount
+	}{{"min_price", &filter.MinPrice}, {"max_price", &filter.MaxPrice}}
+	for _, p := range prices {
+		if v := c.Query(p.name); v != "" {
+			amount, err := models.ParseAmount(v)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s", p.name)
+			}
+			*p.dst = &amount
+		}
+	}
+	return filter, nil
 }
 
 // GetByID godoc
 // @Summary Get subscription by ID
-// @Description Get subscription details by its ID
+// @Description Get subscription details by its ID. The ETag header carries the subscription version, send it in If-None-Match (or Last-Modified in If-Modified-Since) to get 304 while the subscription is unchanged
 // @Tags subscriptions
 // @Produce json
 // @Param id path int true "Subscription ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Param If-Modified-Since header string false "Last-Modified of a cached copy, ignored when If-None-Match is set"
 // @Success 200 {object} models.Subscription
-// @Failure 400 {object} map[string]string "invalid id"
-// @Failure 404 {object} map[string]string "subscription not found"
-// @Failure 500 {object} map[string]string "internal server error"
+// @Header 200 {string} ETag "Subscription version"
+// @Header 200 {string} Last-Modified "Time of the last change"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} Problem "invalid id"
+// @Failure 401 {object} Problem "missing or invalid credentials"
+// @Failure 403 {object} Problem "the API key lacks the required scope"
+// @Failure 404 {object} Problem "subscription not found"
+// @Failure 429 {object} Problem "rate limit exceeded, see Retry-After"
+// @Failure 500 {object} Problem "internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /subscriptions/{id} [get]
 func (h *SubscriptionHandler) GetByID(c *gin.Context) {
+	ctx, endSpan := startSpan(c, "SubscriptionHandler.GetByID")
+	defer endSpan()
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		c.Error(badRequest("invalid id"))
 		return
 	}
-	sub, err := h.repo.GetByID(c.Request.Context(), id)
+	sub, err := h.getOwned(ctx, c, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
-		} else {
-			log.Printf("Error getting subscription by ID: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+		c.Error(err)
 		return
 	}
-	if sub == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+	setValidators(c, sub)
+	if notModified(c, sub) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 	c.JSON(http.StatusOK, sub)
@@ -102,106 +271,414 @@ func (h *SubscriptionHandler) GetByID(c *gin.Context) {
 
 // Update godoc
 // @Summary Update subscription
-// @Description Update subscription by ID with JSON body
+// @Description Update subscription by ID with JSON body. With If-Match the update is applied only if the subscription version still matches the ETag
 // @Tags subscriptions
 // @Accept json
 // @Param id path int true "Subscription ID"
+// @Param If-Match header string false "ETag from GetByID"
 // @Param subscription body models.Subscription true "Subscription data"
 // @Success 204 "No Content"
-// @Failure 400 {object} map[string]string "invalid id or input"
-// @Failure 404 {object} map[string]string "subscription not found"
-// @Failure 500 {object} map[string]string "internal server error"
+// @Header 204 {string} ETag "New subscription version"
+// @Header 204 {string} Last-Modified "Time of the change"
+// @Failure 400 {object} Problem "invalid id or malformed JSON"
+// @Failure 401 {object} Problem "missing or invalid credentials"
+// @Failure 403 {object} Problem "user_id belongs to another user, or the API key lacks the required scope"
+// @Failure 404 {object} Problem "subscription not found"
+// @Failure 409 {object} Problem "subscription was modified concurrently, retry the request"
+// @Failure 413 {object} Problem "request body too large"
+// @Failure 412 {object} Problem "If-Match does not match the current version"
+// @Failure 422 {object} Problem "validation failed, per-field errors in fields"
+// @Failure 429 {object} Problem "rate limit exceeded, see Retry-After"
+// @Failure 500 {object} Problem "internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /subscriptions/{id} [put]
 func (h *SubscriptionHandler) Update(c *gin.Context) {
+	ctx, endSpan := startSpan(c, "SubscriptionHandler.Update")
+	defer endSpan()
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		c.Error(badRequest("invalid id"))
 		return
 	}
 	var sub models.Subscription
 	if err := c.ShouldBindJSON(&sub); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(bindError(err))
 		return
 	}
-	err = h.repo.Update(c.Request.Context(), id, &sub)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
-		} else {
-			log.Printf("Error updating subscription: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	var version int
+	if scopedUser(c) != uuid.Nil || c.GetHeader("If-Match") != "" {
+		current, err := h.getOwned(ctx, c, id)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		if err := checkUser(c, sub.UserID); err != nil {
+			c.Error(err)
+			return
 		}
+		if version, err = checkIfMatch(c, current); err != nil {
+			c.Error(err)
+			return
+		}
+		version = ownedVersion(c, current, version)
+	}
+	err = conditionalWriteError(c, h.repo.Update(ctx, id, &sub, version))
+	if err != nil {
+		c.Error(err)
 		return
 	}
+	setValidators(c, &sub)
 	c.Status(http.StatusNoContent)
 }
 
+// Patch godoc
+// @Summary Partially update subscription
+// @Description Update only the supplied fields of a subscription. Accepts JSON Merge Patch (RFC 7396, application/merge-patch+json or application/json) and JSON Patch (RFC 6902, application/json-patch+json). Setting end_date to null (or removing it) makes the subscription open-ended again. With If-Match the patch is applied only if the subscription version still matches the ETag
+// @Tags subscriptions
+// @Accept json
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param If-Match header string false "ETag from GetByID"
+// @Param patch body object true "Merge patch object or JSON Patch operations array"
+// @Success 200 {object} models.Subscription
+// @Header 200 {string} ETag "New subscription version"
+// @Header 200 {string} Last-Modified "Time of the last change"
+// @Failure 400 {object} Problem "invalid id or patch"
+// @Failure 401 {object} Problem "missing or invalid credentials"
+// @Failure 403 {object} Problem "user_id belongs to another user, or the API key lacks the required scope"
+// @Failure 404 {object} Problem "subscription not found"
+// @Failure 409 {object} Problem "subscription was modified concurrently, retry the request"
+// @Failure 412 {object} Problem "If-Match does not match the current version"
+// @Failure 413 {object} Problem "request body too large"
+// @Failure 415 {object} Problem "unsupported patch content type"
+// @Failure 422 {object} Problem "patched subscription failed validation, per-field errors in fields"
+// @Failure 429 {object} Problem "rate limit exceeded, see Retry-After"
+// @Failure 500 {object} Problem "internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /subscriptions/{id} [patch]
+func (h *SubscriptionHandler) Patch(c *gin.Context) {
+	ctx, endSpan := startSpan(c, "SubscriptionHandler.Patch")
+	defer endSpan()
+
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.Error(badRequest("invalid id"))
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if !errors.As(err, &maxBytesErr) {
+			err = badRequest("cannot read request body")
+		}
+		c.Error(err)
+		return
+	}
+
+	current, err := h.getOwned(ctx, c, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if _, err := checkIfMatch(c, current); err != nil {
+		c.Error(err)
+		return
+	}
+
+	patched, err := applyPatch(*current, c.ContentType(), body)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if err := checkUser(c, patched.UserID); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := validation.Struct(patched); err != nil {
+		c.Error(bindError(err))
+		return
+	}
+
+	// Патч вычислен от прочитанной версии, поэтому применяется только к ней, даже без If-Match
+	diff := models.DiffSubscriptions(*current, patched)
+	err = conditionalWriteError(c, h.repo.Patch(ctx, id, diff, current.Version))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	result := current
+	if !diff.IsEmpty() {
+		// Версию и время изменения назначает хранилище, поэтому отдаём сохранённое состояние
+		if result, err = h.repo.GetByID(ctx, id); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+	setValidators(c, result)
+	c.JSON(http.StatusOK, result)
+}
+
 // Delete godoc
 // @Summary Delete subscription
-// @Description Delete subscription by ID
+// @Description Delete subscription by ID. With If-Match the subscription is deleted only if its version still matches the ETag
 // @Tags subscriptions
 // @Param id path int true "Subscription ID"
+// @Param If-Match header string false "ETag from GetByID"
 // @Success 204 "No Content"
-// @Failure 400 {object} map[string]string "invalid id"
-// @Failure 404 {object} map[string]string "subscription not found"
-// @Failure 500 {object} map[string]string "internal server error"
+// @Failure 400 {object} Problem "invalid id"
+// @Failure 401 {object} Problem "missing or invalid credentials"
+// @Failure 403 {object} Problem "the API key lacks the required scope"
+// @Failure 404 {object} Problem "subscription not found"
+// @Failure 409 {object} Problem "subscription was modified concurrently, retry the request"
+// @Failure 412 {object} Problem "If-Match does not match the current version"
+// @Failure 429 {object} Problem "rate limit exceeded, see Retry-After"
+// @Failure 500 {object} Problem "internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /subscriptions/{id} [delete]
 func (h *SubscriptionHandler) Delete(c *gin.Context) {
+	ctx, endSpan := startSpan(c, "SubscriptionHandler.Delete")
+	defer endSpan()
+
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		c.Error(badRequest("invalid id"))
 		return
 	}
-	err = h.repo.Delete(c.Request.Context(), id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
-		} else {
-			log.Printf("Error deleting subscription: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	var version int
+	if scopedUser(c) != uuid.Nil || c.GetHeader("If-Match") != "" {
+		current, err := h.getOwned(ctx, c, id)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		if version, err = checkIfMatch(c, current); err != nil {
+			c.Error(err)
+			return
 		}
+		version = ownedVersion(c, current, version)
+	}
+	err = conditionalWriteError(c, h.repo.Delete(ctx, id, version))
+	if err != nil {
+		c.Error(err)
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
 
+// SumResponse — стоимость подписок за период в валюте currency
+type SumResponse struct {
+	Currency          string        `json:"currency" example:"RUB"`
+	Sum               models.Amount `json:"sum" swaggertype:"number" example:"1299.5"`                // сумма списаний, приходящихся на период
+	NormalizedSum     models.Amount `json:"normalized_sum" swaggertype:"number" example:"1299.5"`     // стоимость при помесячной оплате: месячный эквивалент × месяцы активности
+	MonthlyEquivalent models.Amount `json:"monthly_equivalent" swaggertype:"number" example:"108.29"` // NormalizedSum в среднем на один месяц периода
+	ByCurrency        []CurrencySum `json:"by_currency"`                                              // разбивка по валютам подписок
+}
+
+// CurrencySum — стоимость подписок в одной валюте: в исходной валюте и в пересчёте в валюту ответа
+type CurrencySum struct {
+	Currency               string        `json:"currency" example:"USD"`
+	Sum                    models.Amount `json:"sum" swaggertype:"number" example:"9.99"`
+	NormalizedSum          models.Amount `json:"normalized_sum" swaggertype:"number" example:"9.99"`
+	ConvertedSum           models.Amount `json:"converted_sum" swaggertype:"number" example:"899.5"`
+	ConvertedNormalizedSum models.Amount `json:"converted_normalized_sum" swaggertype:"number" example:"899.5"`
+}
+
 // GetSum godoc
 // @Summary Get total cost sum for subscriptions
-// @Description Get sum of subscription prices filtered by start/end dates, user ID and service name
+// @Description Get cost of subscriptions over the period [start, end], filtered by user ID and service name, in the requested currency.
+// @Description sum is the total of actual charges falling into the months the subscription is active within the period: the first charge is on start_date and the next ones follow every billing_interval billing_periods.
+// @Description normalized_sum spreads each price evenly over its billing cycle (a yearly price counts as 1/12 per month), and monthly_equivalent is normalized_sum per month of the period.
+// @Description Costs of each month are converted at the exchange rates effective on the first day of that month, by_currency lists the totals per subscription currency
 // @Tags subscriptions
 // @Produce json
 // @Param start query string true "Start date in MM-YYYY"
 // @Param end query string true "End date in MM-YYYY"
-// @Param user_id query string true "User UUID"
+// @Param user_id query string false "User UUID (required unless the bearer token identifies the user)"
 // @Param service_name query string true "Service Name"
-// @Success 200 {object} map[string]int "sum total cost"
-// @Failure 400 {object} map[string]string "missing or invalid parameters"
-// @Failure 500 {object} map[string]string "internal server error"
+// @Param currency query string false "ISO 4217 currency of the totals, RUB by default"
+// @Success 200 {object} SumResponse
+// @Failure 400 {object} Problem "missing or invalid parameters, or a period longer than 120 months"
+// @Failure 401 {object} Problem "missing or invalid credentials"
+// @Failure 403 {object} Problem "user_id belongs to another user, or the API key lacks the required scope"
+// @Failure 422 {object} Problem "no exchange rate for a currency in one of the months"
+// @Failure 429 {object} Problem "rate limit exceeded, see Retry-After"
+// @Failure 500 {object} Problem "internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /subscriptions/sum [get]
 func (h *SubscriptionHandler) GetSum(c *gin.Context) {
+	ctx, endSpan := startSpan(c, "SubscriptionHandler.GetSum")
+	defer endSpan()
+
 	start := c.Query("start")
 	end := c.Query("end")
 	userIDStr := c.Query("user_id")
 	serviceName := c.Query("service_name")
+	if scope := scopedUser(c); userIDStr == "" && scope != uuid.Nil {
+		// Пользователю с токеном достаточно не указывать user_id — сумма считается по нему самому
+		userIDStr = scope.String()
+	}
 
 	// Проверяем обязательные параметры
 	if start == "" || end == "" || userIDStr == "" || serviceName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "start, end, user_id, and service_name params are required"})
+		c.Error(badRequest("start, end, user_id, and service_name params are required"))
+		return
+	}
+
+	startMonth, endMonth, err := parsePeriod(start, end)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	currency, err := parseCurrency(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		c.Error(badRequest("invalid user_id"))
+		return
+	}
+	if err := checkUser(c, userID); err != nil {
+		c.Error(err)
+		return
+	}
+	costs, err := h.repo.GetCosts(ctx, repository.CostFilter{
+		Start: start, End: end, UserIDs: []uuid.UUID{userID}, ServiceNames: []string{serviceName},
+	})
+	if err != nil {
+		c.Error(err)
 		return
 	}
-	sum, err := h.repo.GetSum(c.Request.Context(), start, end, userID, serviceName)
+	resp, err := h.sumCosts(ctx, costs, currency)
 	if err != nil {
-		log.Printf("Error getting sum: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"sum": sum})
+	months := models.Amount(models.MonthsBetween(startMonth, endMonth))
+	resp.MonthlyEquivalent = (resp.NormalizedSum + months/2) / months
+	c.JSON(http.StatusOK, resp)
+}
+
+// maxPeriodMonths ограничивает длину периода в отчётах: стоимость считается и возвращается по каждому месяцу
+const maxPeriodMonths = 120
+
+// parsePeriod разбирает первый и последний месяц периода в формате MM-YYYY
+func parsePeriod(start, end string) (startMonth, endMonth time.Time, err error) {
+	if startMonth, err = models.ParseMonth(start); err != nil {
+		return startMonth, endMonth, badRequest("invalid start, expected MM-YYYY")
+	}
+	if endMonth, err = models.ParseMonth(end); err != nil {
+		return startMonth, endMonth, badRequest("invalid end, expected MM-YYYY")
+	}
+	if endMonth.Before(startMonth) {
+		return startMonth, endMonth, badRequest("end must not be before start")
+	}
+	if models.MonthsBetween(startMonth, endMonth) > maxPeriodMonths {
+		return startMonth, endMonth, badRequest(fmt.Sprintf("period must not exceed %d months", maxPeriodMonths))
+	}
+	return startMonth, endMonth, nil
+}
+
+// parseCurrency разбирает query-параметр currency — валюту, в которой возвращаются суммы
+func parseCurrency(c *gin.Context) (string, error) {
+	currency := strings.ToUpper(c.DefaultQuery("currency", models.BaseCurrency))
+	if !validation.Currency(currency) {
+		return "", badRequest("invalid currency, expected ISO 4217 code")
+	}
+	return currency, nil
+}
+
+// rateTable загружает курсы валют, нужные для пересчёта costs в валюту target: только на месяцы из costs,
+// а не всю историю курсов
+func (h *SubscriptionHandler) rateTable(ctx context.Context, costs []repository.MonthlyCost, target string) (*exchange.Table, error) {
+	if len(costs) == 0 {
+		return exchange.NewTable(nil), nil
+	}
+	currencies := map[string]bool{target: true}
+	from, to := costs[0].Month, costs[0].Month
+	for _, cost := range costs {
+		currencies[cost.Charged.Currency] = true
+		if cost.Month.Before(from) {
+			from = cost.Month
+		}
+		if cost.Month.After(to) {
+			to = cost.Month
+		}
+	}
+	delete(currencies, models.BaseCurrency)
+	if len(currencies) == 0 {
+		return exchange.NewTable(nil), nil
+	}
+
+	rates, err := h.rates.ListRange(ctx, slices.Sorted(maps.Keys(currencies)), from, to)
+	if err != nil {
+		return nil, err
+	}
+	return exchange.NewTable(rates), nil
+}
+
+// sumCosts складывает помесячную стоимость в валюте target. Стоимость каждого месяца пересчитывается
+// по курсам на его первое число, итог в валюте target — сумма округлённых итогов по валютам
+func (h *SubscriptionHandler) sumCosts(ctx context.Context, costs []repository.MonthlyCost, target string) (SumResponse, error) {
+	table, err := h.rateTable(ctx, costs, target)
+	if err != nil {
+		return SumResponse{}, err
+	}
+
+	type converted struct{ charged, normalized float64 }
+	sums := make(map[string]*CurrencySum)
+	totals := make(map[string]*converted)
+	for _, cost := range costs {
+		currency := cost.Charged.Currency
+		sum, ok := sums[currency]
+		if !ok {
+			sum = &CurrencySum{Currency: currency}
+			sums[currency] = sum
+			totals[currency] = &converted{}
+		}
+		sum.Sum += cost.Charged.Amount
+		sum.NormalizedSum += cost.Normalized.Amount
+
+		charged, err := table.Convert(cost.Charged.Amount.Float64(), currency, target, cost.Month)
+		if err != nil {
+			return SumResponse{}, rateError(err)
+		}
+		normalized, err := table.Convert(cost.Normalized.Amount.Float64(), currency, target, cost.Month)
+		if err != nil {
+			return SumResponse{}, rateError(err)
+		}
+		totals[currency].charged += charged
+		totals[currency].normalized += normalized
+	}
+
+	resp := SumResponse{Currency: target, ByCurrency: make([]CurrencySum, 0, len(sums))}
+	for currency, sum := range sums {
+		sum.ConvertedSum = models.AmountFromFloat(totals[currency].charged)
+		sum.ConvertedNormalizedSum = models.AmountFromFloat(totals[currency].normalized)
+		resp.Sum += sum.ConvertedSum
+		resp.NormalizedSum += sum.ConvertedNormalizedSum
+		resp.ByCurrency = append(resp.ByCurrency, *sum)
+	}
+	sort.Slice(resp.ByCurrency, func(i, j int) bool { return resp.ByCurrency[i].Currency < resp.ByCurrency[j].Currency })
+	return resp, nil
+}
+
+// rateError превращает отсутствие курса в ответ 422: запрос корректен, но посчитать сумму без курса нельзя
+func rateError(err error) error {
+	if errors.Is(err, exchange.ErrNoRate) {
+		return &httpError{status: http.StatusUnprocessableEntity, detail: err.Error()}
+	}
+	return err
 }
@@ -3,45 +3,79 @@ package handlers
 import (
 	"bytes"
 	"context"
-	"database/sql"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"rest-service/internal/models"
+	"rest-service/internal/repository"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // MockSubscriptionRepository — mock реализации интерфейса
 type MockSubscriptionRepository struct {
-	CreateFunc  func(ctx context.Context, sub *models.Subscription) (int, error)
-	GetAllFunc  func(ctx context.Context) ([]models.Subscription, error)
-	GetByIDFunc func(ctx context.Context, id int) (*models.Subscription, error)
-	UpdateFunc  func(ctx context.Context, id int, sub *models.Subscription) error
-	DeleteFunc  func(ctx context.Context, id int) error
-	GetSumFunc  func(ctx context.Context, start, end string, userID uuid.UUID, serviceName string) (int, error)
+	CreateFunc   func(ctx context.Context, sub *models.Subscription) (int, error)
+	GetAllFunc   func(ctx context.Context, filter repository.SubscriptionFilter) (*repository.SubscriptionPage, error)
+	GetByIDFunc  func(ctx context.Context, id int) (*models.Subscription, error)
+	UpdateFunc   func(ctx context.Context, id int, sub *models.Subscription, version int) error
+	PatchFunc    func(ctx context.Context, id int, patch models.SubscriptionPatch, version int) error
+	DeleteFunc   func(ctx context.Context, id int, version int) error
+	GetCostsFunc func(ctx context.Context, filter repository.CostFilter) ([]repository.MonthlyCost, error)
+
+	GetServiceStatsFunc    func(ctx context.Context, month string) ([]repository.ServiceStats, error)
+	CountSubscriptionsFunc func(ctx context.Context, filter repository.CostFilter) ([]repository.SubscriptionCount, error)
 }
 
 func (m *MockSubscriptionRepository) Create(ctx context.Context, sub *models.Subscription) (int, error) {
 	return m.CreateFunc(ctx, sub)
 }
-func (m *MockSubscriptionRepository) GetAll(ctx context.Context) ([]models.Subscription, error) {
-	return m.GetAllFunc(ctx)
+func (m *MockSubscriptionRepository) GetAll(ctx context.Context, filter repository.SubscriptionFilter) (*repository.SubscriptionPage, error) {
+	return m.GetAllFunc(ctx, filter)
 }
 func (m *MockSubscriptionRepository) GetByID(ctx context.Context, id int) (*models.Subscription, error) {
 	return m.GetByIDFunc(ctx, id)
 }
-func (m *MockSubscriptionRepository) Update(ctx context.Context, id int, sub *models.Subscription) error {
-	return m.UpdateFunc(ctx, id, sub)
+func (m *MockSubscriptionRepository) Update(ctx context.Context, id int, sub *models.Subscription, version int) error {
+	return m.UpdateFunc(ctx, id, sub, version)
+}
+func (m *MockSubscriptionRepository) Patch(ctx context.Context, id int, patch models.SubscriptionPatch, version int) error {
+	return m.PatchFunc(ctx, id, patch, version)
+}
+func (m *MockSubscriptionRepository) Delete(ctx context.Context, id int, version int) error {
+	return m.DeleteFunc(ctx, id, version)
+}
+func (m *MockSubscriptionRepository) GetCosts(ctx context.Context, filter repository.CostFilter) ([]repository.MonthlyCost, error) {
+	return m.GetCostsFunc(ctx, filter)
 }
-func (m *MockSubscriptionRepository) Delete(ctx context.Context, id int) error {
-	return m.DeleteFunc(ctx, id)
+func (m *MockSubscriptionRepository) GetServiceStats(ctx context.Context, month string) ([]repository.ServiceStats, error) {
+	return m.GetServiceStatsFunc(ctx, month)
+}
+func (m *MockSubscriptionRepository) CountSubscriptions(ctx context.Context, filter repository.CostFilter) ([]repository.SubscriptionCount, error) {
+	return m.CountSubscriptionsFunc(ctx, filter)
+}
+
+// monthlyCost — стоимость за месяц month в валюте currency, суммы в сотых долях
+func monthlyCost(month time.Time, currency string, charged, normalized models.Amount) repository.MonthlyCost {
+	return repository.MonthlyCost{
+		Month:      month,
+		Charged:    models.Money{Amount: charged, Currency: currency},
+		Normalized: models.Money{Amount: normalized, Currency: currency},
+	}
 }
-func (m *MockSubscriptionRepository) GetSum(ctx context.Context, start, end string, userID uuid.UUID, serviceName string) (int, error) {
-	return m.GetSumFunc(ctx, start, end, userID, serviceName)
+
+// newTestRouter создаёт роутер с теми же middleware обработки ошибок, что и в main
+func newTestRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(RequestID(), ErrorMiddleware())
+	return router
 }
 
 func TestSubscriptionHandler_Create(t *testing.T) {
@@ -51,11 +85,11 @@ func TestSubscriptionHandler_Create(t *testing.T) {
 			return 1, nil
 		},
 	}
-	handler := NewSubscriptionHandler(mockRepo)
-	router := gin.New()
+	handler := NewSubscriptionHandler(mockRepo, repository.NewMemoryExchangeRateRepository())
+	router := newTestRouter()
 	router.POST("/subscriptions", handler.Create)
 
-	sub := models.Subscription{UserID: uuid.New(), ServiceName: "Test", Price: 10}
+	sub := models.Subscription{UserID: uuid.New(), ServiceName: "Test", Price: 10, StartDate: "07-2025"}
 	body, _ := json.Marshal(sub)
 	req, _ := http.NewRequest("POST", "/subscriptions", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
@@ -68,19 +102,62 @@ func TestSubscriptionHandler_Create(t *testing.T) {
 	assert.Equal(t, float64(1), resp["id"])
 }
 
+func TestSubscriptionHandler_Create_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewSubscriptionHandler(&MockSubscriptionRepository{}, repository.NewMemoryExchangeRateRepository())
+	router := newTestRouter()
+	router.POST("/subscriptions", handler.Create)
+
+	body := `{"service_name": "", "price": -1, "start_date": "07-2025", "end_date": "01-2025"}`
+	req, _ := http.NewRequest("POST", "/subscriptions", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var resp struct {
+		Errors []struct {
+			Field string `json:"field"`
+		} `json:"errors"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	var fields []string
+	for _, f := range resp.Errors {
+		fields = append(fields, f.Field)
+	}
+	assert.ElementsMatch(t, []string{"service_name", "price", "user_id", "end_date"}, fields)
+
+	// Цена точнее копеек не округляется молча
+	req, _ = http.NewRequest("POST", "/subscriptions", bytes.NewBufferString(`{"service_name": "Netflix", "price": 399.999}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "at most 2 decimal places")
+
+	// Некорректный JSON — 400
+	req, _ = http.NewRequest("POST", "/subscriptions", bytes.NewBufferString(`{"price":`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestSubscriptionHandler_GetAll(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	subs := []models.Subscription{{ID: 1, UserID: uuid.New(), ServiceName: "Test", Price: 10}}
+	var got repository.SubscriptionFilter
 	mockRepo := &MockSubscriptionRepository{
-		GetAllFunc: func(ctx context.Context) ([]models.Subscription, error) {
-			return subs, nil
+		GetAllFunc: func(ctx context.Context, filter repository.SubscriptionFilter) (*repository.SubscriptionPage, error) {
+			got = filter
+			return &repository.SubscriptionPage{Items: subs, Total: 3, NextCursor: "abc"}, nil
 		},
 	}
-	handler := NewSubscriptionHandler(mockRepo)
-	router := gin.New()
+	handler := NewSubscriptionHandler(mockRepo, repository.NewMemoryExchangeRateRepository())
+	router := newTestRouter()
 	router.GET("/subscriptions", handler.GetAll)
 
-	req, _ := http.NewRequest("GET", "/subscriptions", nil)
+	req, _ := http.NewRequest("GET", "/subscriptions?service_name_prefix=Te&min_price=5&sort=price&order=desc&limit=1&offset=2", nil)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
@@ -89,6 +166,31 @@ func TestSubscriptionHandler_GetAll(t *testing.T) {
 	json.Unmarshal(w.Body.Bytes(), &resp)
 	assert.Len(t, resp, 1)
 	assert.Equal(t, "Test", resp[0].ServiceName)
+	assert.Equal(t, "3", w.Header().Get("X-Total-Count"))
+	assert.Equal(t, `</subscriptions?cursor=abc&limit=1&min_price=5&order=desc&service_name_prefix=Te&sort=price>; rel="next"`, w.Header().Get("Link"))
+
+	assert.Equal(t, "Te", got.ServiceNamePrefix)
+	assert.Equal(t, models.Units(5), *got.MinPrice)
+	assert.Nil(t, got.MaxPrice)
+	assert.Equal(t, "price", got.Sort)
+	assert.Equal(t, "desc", got.Order)
+	assert.Equal(t, 1, got.Limit)
+	assert.Equal(t, 2, got.Offset)
+	assert.True(t, got.UpdatedSince.IsZero())
+
+	req, _ = http.NewRequest("GET", "/subscriptions?updated_since=2025-10-01T12:00:00%2B03:00", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, got.UpdatedSince.Equal(time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC)))
+
+	// Тест на 400 при некорректных параметрах
+	for _, query := range []string{"sort=password", "order=up", "limit=5000", "active_in=2025-01", "user_id=42", "created_since=2025-10-01"} {
+		req, _ = http.NewRequest("GET", "/subscriptions?"+query, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
 }
 
 func TestSubscriptionHandler_GetByID(t *testing.T) {
@@ -99,11 +201,11 @@ func TestSubscriptionHandler_GetByID(t *testing.T) {
 			if id == 1 {
 				return sub, nil
 			}
-			return nil, sql.ErrNoRows
+			return nil, repository.ErrNotFound
 		},
 	}
-	handler := NewSubscriptionHandler(mockRepo)
-	router := gin.New()
+	handler := NewSubscriptionHandler(mockRepo, repository.NewMemoryExchangeRateRepository())
+	router := newTestRouter()
 	router.GET("/subscriptions/:id", handler.GetByID)
 
 	req, _ := http.NewRequest("GET", "/subscriptions/1", nil)
@@ -126,18 +228,18 @@ func TestSubscriptionHandler_GetByID(t *testing.T) {
 func TestSubscriptionHandler_Update(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockRepo := &MockSubscriptionRepository{
-		UpdateFunc: func(ctx context.Context, id int, sub *models.Subscription) error {
+		UpdateFunc: func(ctx context.Context, id int, sub *models.Subscription, version int) error {
 			if id != 1 {
-				return sql.ErrNoRows
+				return repository.ErrNotFound
 			}
 			return nil
 		},
 	}
-	handler := NewSubscriptionHandler(mockRepo)
-	router := gin.New()
+	handler := NewSubscriptionHandler(mockRepo, repository.NewMemoryExchangeRateRepository())
+	router := newTestRouter()
 	router.PUT("/subscriptions/:id", handler.Update)
 
-	updateSub := models.Subscription{ServiceName: "Updated", Price: 20, UserID: uuid.New()}
+	updateSub := models.Subscription{ServiceName: "Updated", Price: 20, UserID: uuid.New(), StartDate: "07-2025"}
 	body, _ := json.Marshal(updateSub)
 	req, _ := http.NewRequest("PUT", "/subscriptions/1", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
@@ -153,18 +255,80 @@ func TestSubscriptionHandler_Update(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
+func TestSubscriptionHandler_Patch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	endDate := "12-2025"
+	stored := repository.NewMemorySubscriptionRepository()
+	stored.Create(context.Background(), &models.Subscription{UserID: uuid.New(), ServiceName: "Test", Price: 10, StartDate: "01-2025", EndDate: &endDate})
+	var got models.SubscriptionPatch
+	mockRepo := &MockSubscriptionRepository{
+		GetByIDFunc: stored.GetByID,
+		PatchFunc: func(ctx context.Context, id int, patch models.SubscriptionPatch, version int) error {
+			got = patch
+			return stored.Patch(ctx, id, patch, version)
+		},
+	}
+	handler := NewSubscriptionHandler(mockRepo, repository.NewMemoryExchangeRateRepository())
+	router := newTestRouter()
+	router.PATCH("/subscriptions/:id", handler.Patch)
+
+	send := func(path, contentType, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PATCH", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Merge Patch: меняется только цена, end_date явно сбрасывается
+	w := send("/subscriptions/1", "application/merge-patch+json", `{"price": 20.5, "end_date": null}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.Amount(2050), *got.Price)
+	assert.True(t, got.ClearEndDate)
+	assert.Nil(t, got.ServiceName)
+	assert.Nil(t, got.StartDate)
+	var resp models.Subscription
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, models.Amount(2050), resp.Price)
+	assert.Nil(t, resp.EndDate)
+	assert.Equal(t, 2, resp.Version)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	// JSON Patch: test + add (end_date сброшен предыдущим патчем)
+	w = send("/subscriptions/1", "application/json-patch+json",
+		`[{"op": "test", "path": "/service_name", "value": "Test"}, {"op": "add", "path": "/end_date", "value": "06-2025"}]`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "06-2025", *got.EndDate)
+	assert.Nil(t, got.Price)
+
+	// Неудачная операция test
+	w = send("/subscriptions/1", "application/json-patch+json", `[{"op": "test", "path": "/price", "value": 99}]`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Неизвестное поле и смена id
+	assert.Equal(t, http.StatusBadRequest, send("/subscriptions/1", "application/merge-patch+json", `{"color": "red"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send("/subscriptions/1", "application/merge-patch+json", `{"id": 2}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send("/subscriptions/1", "application/merge-patch+json", `{"updated_at": "2020-01-01T00:00:00Z"}`).Code)
+
+	// Патч, нарушающий правила валидации
+	assert.Equal(t, http.StatusUnprocessableEntity, send("/subscriptions/1", "application/merge-patch+json", `{"end_date": "12-2024"}`).Code)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, send("/subscriptions/1", "text/plain", `price=1`).Code)
+	assert.Equal(t, http.StatusNotFound, send("/subscriptions/999", "application/merge-patch+json", `{"price": 1}`).Code)
+}
+
 func TestSubscriptionHandler_Delete(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockRepo := &MockSubscriptionRepository{
-		DeleteFunc: func(ctx context.Context, id int) error {
+		DeleteFunc: func(ctx context.Context, id int, version int) error {
 			if id != 1 {
-				return sql.ErrNoRows
+				return repository.ErrNotFound
 			}
 			return nil
 		},
 	}
-	handler := NewSubscriptionHandler(mockRepo)
-	router := gin.New()
+	handler := NewSubscriptionHandler(mockRepo, repository.NewMemoryExchangeRateRepository())
+	router := newTestRouter()
 	router.DELETE("/subscriptions/:id", handler.Delete)
 
 	req, _ := http.NewRequest("DELETE", "/subscriptions/1", nil)
@@ -185,16 +349,16 @@ func TestSubscriptionHandler_GetSum(t *testing.T) {
 	fixedUUID := uuid.New() // фиксируем UUID для запроса и мока
 
 	mockRepo := &MockSubscriptionRepository{
-		GetSumFunc: func(ctx context.Context, start, end string, userID uuid.UUID, serviceName string) (int, error) {
+		GetCostsFunc: func(ctx context.Context, filter repository.CostFilter) ([]repository.MonthlyCost, error) {
 			// Проверяем, что приходит ожидаемый uuid
-			if userID != fixedUUID {
-				return 0, nil
+			if len(filter.UserIDs) != 1 || filter.UserIDs[0] != fixedUUID {
+				return nil, nil
 			}
-			return 150, nil
+			return []repository.MonthlyCost{monthlyCost(time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC), "RUB", 15000, 120000)}, nil
 		},
 	}
-	handler := NewSubscriptionHandler(mockRepo)
-	router := gin.New()
+	handler := NewSubscriptionHandler(mockRepo, repository.NewMemoryExchangeRateRepository())
+	router := newTestRouter()
 	router.GET("/subscriptions/total-cost", handler.GetSum)
 
 	url := "/subscriptions/total-cost?start=01-2023&end=12-2023&user_id=" + fixedUUID.String() + "&service_name=Netflix"
@@ -204,8 +368,162 @@ func TestSubscriptionHandler_GetSum(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	var resp map[string]int
+	var resp SumResponse
 	err := json.Unmarshal(w.Body.Bytes(), &resp)
 	assert.NoError(t, err)
-	assert.Equal(t, 150, resp["sum"])
+	assert.Equal(t, SumResponse{
+		Currency: "RUB", Sum: 15000, NormalizedSum: 120000, MonthlyEquivalent: 10000,
+		ByCurrency: []CurrencySum{{Currency: "RUB", Sum: 15000, NormalizedSum: 120000, ConvertedSum: 15000, ConvertedNormalizedSum: 120000}},
+	}, resp)
+}
+
+func TestSubscriptionHandler_GetSum_Currency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	month := func(m time.Month) time.Time { return time.Date(2023, m, 1, 0, 0, 0, 0, time.UTC) }
+	rates := repository.NewMemoryExchangeRateRepository()
+	require.NoError(t, rates.Upsert(context.Background(), []models.ExchangeRate{
+		{Currency: "USD", Date: month(time.January), Rate: 80},
+		{Currency: "USD", Date: month(time.February), Rate: 90},
+		{Currency: "EUR", Date: time.Date(2022, time.December, 30, 0, 0, 0, 0, time.UTC), Rate: 100},
+	}))
+	handler := NewSubscriptionHandler(&MockSubscriptionRepository{
+		GetCostsFunc: func(ctx context.Context, filter repository.CostFilter) ([]repository.MonthlyCost, error) {
+			return []repository.MonthlyCost{
+				monthlyCost(month(time.January), "RUB", 100000, 50000),
+				monthlyCost(month(time.January), "USD", 1000, 500),
+				monthlyCost(month(time.February), "RUB", 0, 50000),
+				monthlyCost(month(time.February), "USD", 0, 500),
+			}, nil
+		},
+	}, rates)
+	router := newTestRouter()
+	router.GET("/subscriptions/sum", handler.GetSum)
+
+	get := func(currency string) *httptest.ResponseRecorder {
+		url := "/subscriptions/sum?start=01-2023&end=02-2023&service_name=Netflix&user_id=" + uuid.NewString() + "&currency=" + currency
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w
+	}
+
+	// Каждый месяц пересчитывается по курсу на его первое число: 5 USD в январе — 4 EUR, в феврале — 4.5 EUR
+	w := get("eur")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp SumResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, SumResponse{
+		Currency: "EUR", Sum: 1800, NormalizedSum: 1850, MonthlyEquivalent: 925,
+		ByCurrency: []CurrencySum{
+			{Currency: "RUB", Sum: 100000, NormalizedSum: 100000, ConvertedSum: 1000, ConvertedNormalizedSum: 1000},
+			{Currency: "USD", Sum: 1000, NormalizedSum: 1000, ConvertedSum: 800, ConvertedNormalizedSum: 850},
+		},
+	}, resp)
+
+	w = get("GBP")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "no exchange rate for GBP on 2023-01-01")
+
+	assert.Equal(t, http.StatusBadRequest, get("rubles").Code)
+}
+
+func TestSubscriptionHandler_GetSum_InvalidPeriod(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewSubscriptionHandler(&MockSubscriptionRepository{}, repository.NewMemoryExchangeRateRepository())
+	router := newTestRouter()
+	router.GET("/subscriptions/sum", handler.GetSum)
+
+	userID := uuid.New().String()
+	for _, query := range []string{
+		"start=2023-01&end=12-2023",
+		"start=01-2023&end=13-2023",
+		"start=12-2023&end=01-2023",
+		"start=01-2013&end=12-2023",
+	} {
+		req, _ := http.NewRequest("GET", "/subscriptions/sum?"+query+"&user_id="+userID+"&service_name=Netflix", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestErrorMiddleware_ProblemJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockRepo := &MockSubscriptionRepository{
+		GetByIDFunc: func(ctx context.Context, id int) (*models.Subscription, error) {
+			if id == 1 {
+				return nil, errors.New(`pq: relation "subscriptions" does not exist`)
+			}
+			return nil, repository.ErrNotFound
+		},
+	}
+	handler := NewSubscriptionHandler(mockRepo, repository.NewMemoryExchangeRateRepository())
+	router := newTestRouter()
+	router.GET("/subscriptions/:id", handler.GetByID)
+
+	// Внутренняя ошибка не раскрывается клиенту
+	req, _ := http.NewRequest("GET", "/subscriptions/1", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.Equal(t, "req-42", w.Header().Get(RequestIDHeader))
+	assert.NotContains(t, w.Body.String(), "pq:")
+	var problem Problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, ProblemInternal, problem.Type)
+	assert.Equal(t, "/subscriptions/1", problem.Instance)
+	assert.Equal(t, "req-42", problem.RequestID)
+
+	req, _ = http.NewRequest("GET", "/subscriptions/2", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, ProblemNotFound, problem.Type)
+	assert.NotEmpty(t, w.Header().Get(RequestIDHeader))
+
+	req, _ = http.NewRequest("GET", "/subscriptions/abc", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, ProblemBadRequest, problem.Type)
+	assert.Equal(t, "invalid id", problem.Detail)
+}
+
+func TestMaxBodySize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewSubscriptionHandler(&MockSubscriptionRepository{
+		GetByIDFunc: func(ctx context.Context, id int) (*models.Subscription, error) {
+			return &models.Subscription{ID: id, ServiceName: "Yandex Plus", Price: 400, StartDate: "07-2025"}, nil
+		},
+	}, repository.NewMemoryExchangeRateRepository())
+	router := newTestRouter()
+	router.Use(MaxBodySize(64))
+	router.POST("/subscriptions", handler.Create)
+	router.PATCH("/subscriptions/:id", handler.Patch)
+
+	body := `{"service_name": "` + strings.Repeat("a", 100) + `"}`
+	for _, method := range []string{"POST", "PATCH"} {
+		path := "/subscriptions"
+		if method == "PATCH" {
+			path += "/1"
+		}
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, method)
+		assert.Contains(t, w.Body.String(), ProblemPayloadTooLarge, method)
+
+		// Тело без Content-Length обрезается при чтении
+		req, _ = http.NewRequest(method, path, io.NopCloser(strings.NewReader(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.ContentLength = -1
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, method)
+	}
 }
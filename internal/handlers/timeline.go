@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"net/http"
+	"rest-service/internal/exchange"
+	"rest-service/internal/models"
+	"rest-service/internal/repository"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TimelineResponse — помесячная стоимость подписок за период в валюте currency
+type TimelineResponse struct {
+	Currency string          `json:"currency" example:"RUB"`
+	GroupBy  string          `json:"group_by,omitempty" example:"service_name"`
+	Months   []TimelineMonth `json:"months"` // все месяцы периода по порядку, в том числе без подписок
+}
+
+// TimelineMonth — стоимость подписок за один месяц
+type TimelineMonth struct {
+	Month         string          `json:"month" example:"01-2025"`
+	Sum           models.Amount   `json:"sum" swaggertype:"number" example:"1299.5"`            // сумма списаний в этом месяце
+	NormalizedSum models.Amount   `json:"normalized_sum" swaggertype:"number" example:"1299.5"` // сумма месячных эквивалентов цен активных подписок
+	Groups        []TimelineGroup `json:"groups,omitempty"`                                     // разбивка по group_by, только группы с подписками в этом месяце
+}
+
+// TimelineGroup — стоимость подписок одного сервиса или пользователя за месяц
+type TimelineGroup struct {
+	Key           string        `json:"key" example:"Netflix"` // имя сервиса или UUID пользователя
+	Sum           models.Amount `json:"sum" swaggertype:"number" example:"399.99"`
+	NormalizedSum models.Amount `json:"normalized_sum" swaggertype:"number" example:"399.99"`
+}
+
+// GetTimeline godoc
+// @Summary Get cost of subscriptions per month
+// @Description Get cost of subscriptions for every month of the period [start, end] in the requested currency, optionally filtered by any number of users and services and broken down by service or user.
+// @Description sum and normalized_sum of a month have the same meaning as in /subscriptions/sum, costs are converted at the exchange rates effective on the first day of the month.
+// @Description Months without active subscriptions are listed with zero cost. A user identified by the bearer token only sees their own subscriptions
+// @Tags subscriptions
+// @Produce json
+// @Param start query string true "Start date in MM-YYYY"
+// @Param end query string true "End date in MM-YYYY"
+// @Param user_id query []string false "User UUIDs, all users when omitted" collectionFormat(multi)
+// @Param service_name query []string false "Service names, all services when omitted" collectionFormat(multi)
+// @Param group_by query string false "Breakdown of every month" Enums(service_name, user_id)
+// @Param currency query string false "ISO 4217 currency of the totals, RUB by default"
+// @Success 200 {object} TimelineResponse
+// @Failure 400 {object} Problem "missing or invalid parameters, or a period longer than 120 months"
+// @Failure 401 {object} Problem "missing or invalid credentials"
+// @Failure 403 {object} Problem "user_id belongs to another user, or the API key lacks the required scope"
+// @Failure 422 {object} Problem "no exchange rate for a currency in one of the months"
+// @Failure 429 {object} Problem "rate limit exceeded, see Retry-After"
+// @Failure 500 {object} Problem "internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /subscriptions/timeline [get]
+func (h *SubscriptionHandler) GetTimeline(c *gin.Context) {
+	ctx, endSpan := startSpan(c, "SubscriptionHandler.GetTimeline")
+	defer endSpan()
+
+	start := c.Query("start")
+	end := c.Query("end")
+	if start == "" || end == "" {
+		c.Error(badRequest("start and end params are required"))
+		return
+	}
+	startMonth, endMonth, err := parsePeriod(start, end)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	currency, err := parseCurrency(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	groupBy := repository.CostGroup(c.Query("group_by"))
+	if !groupBy.Valid() {
+		c.Error(badRequest("invalid group_by, expected service_name or user_id"))
+		return
+	}
+
+	userIDs, serviceNames, err := costScope(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	costs, err := h.repo.GetCosts(ctx, repository.CostFilter{
+		Start: start, End: end, UserIDs: userIDs, ServiceNames: serviceNames, GroupBy: groupBy,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	table, err := h.rateTable(ctx, costs, currency)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	months, err := timelineMonths(costs, startMonth, endMonth, table, currency, groupBy != repository.CostGroupNone)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, TimelineResponse{Currency: currency, GroupBy: string(groupBy), Months: months})
+}
+
+// timelineMonths раскладывает стоимость по всем месяцам периода [start, end] в валюте target.
+// Стоимость группы пересчитывается по курсам на первое число месяца, итог месяца — сумма округлённых итогов групп
+func timelineMonths(costs []repository.MonthlyCost, start, end time.Time, table *exchange.Table, target string, grouped bool) ([]TimelineMonth, error) {
+	months := make([]TimelineMonth, 0, models.MonthsBetween(start, end))
+	index := make(map[string]int)
+	for month := start; !month.After(end); month = month.AddDate(0, 1, 0) {
+		index[month.Format(models.MonthLayout)] = len(months)
+		months = append(months, TimelineMonth{Month: month.Format(models.MonthLayout)})
+	}
+
+	type key struct{ month, group string }
+	type converted struct{ charged, normalized float64 }
+	totals := make(map[key]*converted)
+	var keys []key
+	for _, cost := range costs {
+		charged, err := table.Convert(cost.Charged.Amount.Float64(), cost.Charged.Currency, target, cost.Month)
+		if err != nil {
+			return nil, rateError(err)
+		}
+		normalized, err := table.Convert(cost.Normalized.Amount.Float64(), cost.Normalized.Currency, target, cost.Month)
+		if err != nil {
+			return nil, rateError(err)
+		}
+		k := key{cost.Month.Format(models.MonthLayout), cost.Group}
+		total, ok := totals[k]
+		if !ok {
+			total = &converted{}
+			totals[k] = total
+			keys = append(keys, k)
+		}
+		total.charged += charged
+		total.normalized += normalized
+	}
+
+	// costs упорядочены по месяцам и группам, поэтому и группы внутри месяца идут по порядку
+	for _, k := range keys {
+		month := &months[index[k.month]]
+		group := TimelineGroup{
+			Key:           k.group,
+			Sum:           models.AmountFromFloat(totals[k].charged),
+			NormalizedSum: models.AmountFromFloat(totals[k].normalized),
+		}
+		month.Sum += group.Sum
+		month.NormalizedSum += group.NormalizedSum
+		if grouped {
+			month.Groups = append(month.Groups, group)
+		}
+	}
+	return months, nil
+}
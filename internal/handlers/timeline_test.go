@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rest-service/internal/models"
+	"rest-service/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionHandler_GetTimeline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	repo := repository.NewMemorySubscriptionRepository()
+	userID := uuid.New()
+	end := "02-2025"
+	repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: 39999, UserID: userID, StartDate: "01-2025", EndDate: &end})
+	repo.Create(ctx, &models.Subscription{ServiceName: "Spotify", Price: 1000, Currency: "USD", UserID: userID, StartDate: "02-2025"})
+	repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: 50000, UserID: uuid.New(), StartDate: "01-2025"})
+
+	rates := repository.NewMemoryExchangeRateRepository()
+	require.NoError(t, rates.Upsert(ctx, []models.ExchangeRate{
+		{Currency: "USD", Date: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), Rate: 100},
+	}))
+	handler := NewSubscriptionHandler(repo, rates)
+	router := newTestRouter()
+	router.GET("/subscriptions/timeline", handler.GetTimeline)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/subscriptions/timeline?"+query, nil))
+		return w
+	}
+
+	// Все месяцы периода, в том числе без подписок, с разбивкой по сервисам
+	w := get("start=12-2024&end=03-2025&user_id=" + userID.String() + "&group_by=service_name")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp TimelineResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, TimelineResponse{
+		Currency: "RUB",
+		GroupBy:  "service_name",
+		Months: []TimelineMonth{
+			{Month: "12-2024"},
+			{Month: "01-2025", Sum: 39999, NormalizedSum: 39999, Groups: []TimelineGroup{
+				{Key: "Netflix", Sum: 39999, NormalizedSum: 39999},
+			}},
+			{Month: "02-2025", Sum: 139999, NormalizedSum: 139999, Groups: []TimelineGroup{
+				{Key: "Netflix", Sum: 39999, NormalizedSum: 39999},
+				{Key: "Spotify", Sum: 100000, NormalizedSum: 100000},
+			}},
+			{Month: "03-2025", Sum: 100000, NormalizedSum: 100000, Groups: []TimelineGroup{
+				{Key: "Spotify", Sum: 100000, NormalizedSum: 100000},
+			}},
+		},
+	}, resp)
+
+	// Без разбивки и фильтров — итог по всем пользователям
+	w = get("start=01-2025&end=01-2025&service_name=Netflix")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"currency":"RUB","months":[{"month":"01-2025","sum":899.99,"normalized_sum":899.99}]}`, w.Body.String())
+
+	for _, query := range []string{
+		"end=01-2025",
+		"start=02-2025&end=01-2025",
+		"start=01-0001&end=12-9999",
+		"start=01-2015&end=01-2025",
+		"start=01-2025&end=01-2025&group_by=currency",
+		"start=01-2025&end=01-2025&user_id=42",
+	} {
+		assert.Equal(t, http.StatusBadRequest, get(query).Code, query)
+	}
+	assert.Equal(t, http.StatusUnprocessableEntity, get("start=01-2025&end=01-2025&currency=EUR").Code)
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var tracer = otel.Tracer("rest-service/internal/handlers")
+
+// startSpan начинает спан обработчика внутри спана HTTP-запроса. Возвращённая функция
+// завершает спан и записывает в него ошибку, добавленную обработчиком через c.Error
+func startSpan(c *gin.Context, name string) (context.Context, func()) {
+	ctx, span := tracer.Start(c.Request.Context(), name)
+	return ctx, func() {
+		if err := c.Errors.Last(); err != nil {
+			span.RecordError(err.Err)
+			if problem := problemFor(err.Err); problem.Status >= 500 {
+				span.SetStatus(codes.Error, problem.Title)
+			}
+		}
+		span.End()
+	}
+}
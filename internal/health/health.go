@@ -0,0 +1,118 @@
+// Package health собирает проверки зависимостей сервиса для проб liveness и readiness
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check проверяет одну зависимость и возвращает ошибку, если она недоступна
+type Check func(ctx context.Context) error
+
+// CheckResult — результат одной проверки. Текст ошибки только пишется в лог:
+// /readyz открыт всем, а ошибки драйвера БД и миграций раскрывают адреса, имена баз и SQL
+type CheckResult struct {
+	Status string `json:"status" example:"ok"`
+	Error  string `json:"-"`
+}
+
+// Report — результат проверки готовности сервиса
+type Report struct {
+	Status string                 `json:"status" example:"ok"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Ready сообщает, прошли ли все проверки
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// Errors возвращает ошибки непрошедших проверок по их именам
+func (r Report) Errors() map[string]string {
+	errs := make(map[string]string)
+	for name, result := range r.Checks {
+		if result.Status != StatusOK {
+			errs[name] = result.Error
+		}
+	}
+	return errs
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker хранит зарегистрированные проверки и признак остановки сервиса
+type Checker struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	checks []namedCheck
+
+	shuttingDown atomic.Bool
+}
+
+// NewChecker создаёт Checker; каждая проверка ограничена timeout
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add регистрирует проверку под именем name
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// SetShuttingDown помечает сервис как останавливающийся: после этого он больше не готов
+// принимать трафик, даже если все зависимости доступны
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Readiness выполняет все проверки параллельно и собирает отчёт
+func (c *Checker) Readiness(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := c.checks
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, nc := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = CheckResult{Status: StatusOK}
+			if err := nc.check(ctx); err != nil {
+				results[i] = CheckResult{Status: StatusFail, Error: err.Error()}
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks)+1)}
+	for i, nc := range checks {
+		report.Checks[nc.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	if c.shuttingDown.Load() {
+		report.Checks["shutdown"] = CheckResult{Status: StatusFail, Error: "server is shutting down"}
+		report.Status = StatusFail
+	} else {
+		report.Checks["shutdown"] = CheckResult{Status: StatusOK}
+	}
+	return report
+}
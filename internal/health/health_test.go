@@ -0,0 +1,51 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker_Readiness(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Add("database", func(ctx context.Context) error { return nil })
+
+	report := checker.Readiness(context.Background())
+	assert.True(t, report.Ready())
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+	assert.Equal(t, StatusOK, report.Checks["shutdown"].Status)
+
+	checker.Add("migrations", func(ctx context.Context) error { return errors.New("schema version 1, expected 3") })
+	report = checker.Readiness(context.Background())
+	assert.False(t, report.Ready())
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+	assert.Equal(t, CheckResult{Status: StatusFail, Error: "schema version 1, expected 3"}, report.Checks["migrations"])
+	assert.Equal(t, map[string]string{"migrations": "schema version 1, expected 3"}, report.Errors())
+}
+
+func TestChecker_ShuttingDown(t *testing.T) {
+	checker := NewChecker(time.Second)
+	assert.True(t, checker.Readiness(context.Background()).Ready())
+
+	checker.SetShuttingDown()
+	report := checker.Readiness(context.Background())
+	assert.False(t, report.Ready())
+	assert.Equal(t, StatusFail, report.Checks["shutdown"].Status)
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	checker := NewChecker(20 * time.Millisecond)
+	checker.Add("database", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	report := checker.Readiness(context.Background())
+	assert.Less(t, time.Since(start), time.Second)
+	assert.False(t, report.Ready())
+	assert.Contains(t, report.Checks["database"].Error, "deadline exceeded")
+}
@@ -0,0 +1,61 @@
+// Package logging — единая точка настройки логов сервиса поверх logrus.
+// Логгер запроса хранится в context.Context, поэтому обработчики и репозитории
+// пишут записи с одним и тем же request_id и trace_id
+package logging
+
+import (
+	"context"
+	stdlog "log"
+	"os"
+	"rest-service/internal/config"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKey struct{}
+
+// Setup настраивает уровень и формат логов и перенаправляет в logrus стандартный пакет log,
+// которым пишут сторонние библиотеки
+func Setup(cfg config.LogConfig) error {
+	level, err := log.ParseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+	log.SetLevel(level)
+	log.SetOutput(os.Stdout)
+	log.SetFormatter(formatter(cfg.Format))
+
+	stdlog.SetFlags(0)
+	stdlog.SetOutput(log.StandardLogger().WriterLevel(log.InfoLevel))
+	return nil
+}
+
+func formatter(format string) log.Formatter {
+	switch format {
+	case config.LogFormatText:
+		return &log.TextFormatter{FullTimestamp: true}
+	case config.LogFormatLogfmt:
+		return &log.TextFormatter{DisableColors: true, FullTimestamp: true, QuoteEmptyFields: true}
+	default:
+		return &log.JSONFormatter{}
+	}
+}
+
+// WithLogger возвращает контекст с логгером запроса
+func WithLogger(ctx context.Context, entry *log.Entry) context.Context {
+	return context.WithValue(ctx, contextKey{}, entry)
+}
+
+// FromContext возвращает логгер запроса из контекста или общий логгер, если его там нет.
+// Если в контексте есть спан OpenTelemetry, к записи добавляются trace_id и span_id
+func FromContext(ctx context.Context) *log.Entry {
+	entry, ok := ctx.Value(contextKey{}).(*log.Entry)
+	if !ok {
+		entry = log.NewEntry(log.StandardLogger())
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		entry = entry.WithFields(log.Fields{"trace_id": sc.TraceID().String(), "span_id": sc.SpanID().String()})
+	}
+	return entry.WithContext(ctx)
+}
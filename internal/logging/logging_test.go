@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"rest-service/internal/config"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&log.JSONFormatter{})
+
+	ctx := WithLogger(context.Background(), logger.WithField("request_id", "req-1"))
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+
+	FromContext(ctx).Info("hello")
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "req-1", entry["request_id"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entry["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", entry["span_id"])
+
+	// Без логгера в контексте используется общий
+	assert.Equal(t, log.StandardLogger(), FromContext(context.Background()).Logger)
+}
+
+func TestFormatter(t *testing.T) {
+	entry := log.NewEntry(log.New()).WithField("status", 200)
+	entry.Message = "Request completed"
+
+	out, err := formatter(config.LogFormatLogfmt).Format(entry)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `msg="Request completed" status=200`)
+
+	out, err = formatter(config.LogFormatJSON).Format(entry)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"status":200`)
+
+	assert.Error(t, Setup(config.LogConfig{Level: "loud", Format: config.LogFormatJSON}))
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+	"rest-service/internal/models"
+	"rest-service/internal/repository"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// businessCollector считает показатели по подпискам в момент сбора метрик
+type businessCollector struct {
+	repo    repository.SubscriptionRepository
+	timeout time.Duration
+	now     func() time.Time
+
+	active       *prometheus.Desc
+	monthlySpend *prometheus.Desc
+}
+
+// RegisterBusiness добавляет метрики по подпискам, активным в текущем месяце:
+// их число по каждому сервису и суммарную месячную стоимость по каждому сервису и валюте
+func (m *Metrics) RegisterBusiness(repo repository.SubscriptionRepository) {
+	m.registry.MustRegister(newBusinessCollector(repo, time.Now))
+}
+
+func newBusinessCollector(repo repository.SubscriptionRepository, now func() time.Time) *businessCollector {
+	return &businessCollector{
+		repo:    repo,
+		timeout: 5 * time.Second,
+		now:     now,
+		active: prometheus.NewDesc("subscriptions_active",
+			"Number of subscriptions active in the current month by service.", []string{"service_name"}, nil),
+		monthlySpend: prometheus.NewDesc("subscriptions_monthly_spend",
+			"Monthly recurring spend of subscriptions active in the current month by service and currency, with non-monthly prices spread evenly over their billing cycle.", []string{"service_name", "currency"}, nil),
+	}
+}
+
+func (c *businessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.active
+	ch <- c.monthlySpend
+}
+
+func (c *businessCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	stats, err := c.repo.GetServiceStats(ctx, c.now().UTC().Format(models.MonthLayout))
+	if err != nil {
+		// Остальные метрики отдаются как обычно, бизнес-метрики в этом сборе пропускаются
+		log.WithError(err).Warn("Failed to collect subscription metrics")
+		return
+	}
+	// Статистика разбита по валютам, а число подписок отдаётся по сервису целиком
+	active := make(map[string]int)
+	for _, s := range stats {
+		active[s.ServiceName] += s.ActiveCount
+		ch <- prometheus.MustNewConstMetric(c.monthlySpend, prometheus.GaugeValue, s.MonthlySpend.Amount.Float64(), s.ServiceName, s.MonthlySpend.Currency)
+	}
+	for service, count := range active {
+		ch <- prometheus.MustNewConstMetric(c.active, prometheus.GaugeValue, float64(count), service)
+	}
+}
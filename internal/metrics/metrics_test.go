@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"rest-service/internal/models"
+	"rest-service/internal/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware_RouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := New()
+	router := gin.New()
+	router.Use(m.Middleware())
+	router.GET("/subscriptions/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for _, path := range []string{"/subscriptions/1", "/subscriptions/2", "/unknown/path"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/subscriptions/:id", "204")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", unmatchedRoute, "404")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.httpDuration))
+}
+
+func TestInstrumentRepository(t *testing.T) {
+	m := New()
+	repo := m.InstrumentRepository(repository.NewMemorySubscriptionRepository())
+	ctx := context.Background()
+
+	_, err := repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: 500, UserID: uuid.New(), StartDate: "01-2025"})
+	require.NoError(t, err)
+	_, err = repo.GetByID(ctx, 42)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	count := func(method, outcome string) uint64 {
+		var metric dto.Metric
+		require.NoError(t, m.repoDuration.WithLabelValues(method, outcome).(prometheus.Histogram).Write(&metric))
+		return metric.GetHistogram().GetSampleCount()
+	}
+	assert.Equal(t, uint64(1), count("Create", "ok"))
+	assert.Equal(t, uint64(1), count("GetByID", "not_found"))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.repoDuration))
+}
+
+func TestBusinessCollector(t *testing.T) {
+	repo := repository.NewMemorySubscriptionRepository()
+	ctx := context.Background()
+	end := "01-2025"
+	repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: models.Units(500), UserID: uuid.New(), StartDate: "01-2025"})
+	repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: models.Units(700), UserID: uuid.New(), StartDate: "12-2024"})
+	repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: models.Units(10), UserID: uuid.New(), StartDate: "12-2024", Currency: "USD"})
+	repo.Create(ctx, &models.Subscription{ServiceName: "Spotify", Price: 200, UserID: uuid.New(), StartDate: "12-2024", EndDate: &end})
+	// Закончилась до текущего месяца
+	repo.Create(ctx, &models.Subscription{ServiceName: "Spotify", Price: 300, UserID: uuid.New(), StartDate: "10-2024", EndDate: &end})
+
+	now := func() time.Time { return time.Date(2025, time.February, 10, 0, 0, 0, 0, time.UTC) }
+	collector := newBusinessCollector(repo, now)
+
+	expected := `
+# HELP subscriptions_active Number of subscriptions active in the current month by service.
+# TYPE subscriptions_active gauge
+subscriptions_active{service_name="Netflix"} 3
+# HELP subscriptions_monthly_spend Monthly recurring spend of subscriptions active in the current month by service and currency, with non-monthly prices spread evenly over their billing cycle.
+# TYPE subscriptions_monthly_spend gauge
+subscriptions_monthly_spend{currency="RUB",service_name="Netflix"} 1200
+subscriptions_monthly_spend{currency="USD",service_name="Netflix"} 10
+`
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	m.RegisterBusiness(repository.NewMemorySubscriptionRepository())
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "go_goroutines")
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"rest-service/internal/models"
+	"rest-service/internal/repository"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// instrumentedRepository измеряет длительность вызовов репозитория
+type instrumentedRepository struct {
+	next     repository.SubscriptionRepository
+	duration *prometheus.HistogramVec
+}
+
+// InstrumentRepository оборачивает репозиторий так, чтобы каждый вызов попадал в repository_call_duration_seconds
+func (m *Metrics) InstrumentRepository(repo repository.SubscriptionRepository) repository.SubscriptionRepository {
+	return &instrumentedRepository{next: repo, duration: m.repoDuration}
+}
+
+func (r *instrumentedRepository) observe(method string, start time.Time, err error) {
+	r.duration.WithLabelValues(method, outcome(err)).Observe(time.Since(start).Seconds())
+}
+
+// outcome сводит ошибку репозитория к небольшому набору значений метки
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, repository.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, repository.ErrConflict):
+		return "conflict"
+	case errors.Is(err, repository.ErrValidation):
+		return "validation"
+	case errors.Is(err, repository.ErrUnavailable):
+		return "unavailable"
+	default:
+		return "error"
+	}
+}
+
+func (r *instrumentedRepository) Create(ctx context.Context, sub *models.Subscription) (id int, err error) {
+	began := time.Now()
+	defer func() { r.observe("Create", began, err) }()
+	return r.next.Create(ctx, sub)
+}
+
+func (r *instrumentedRepository) GetAll(ctx context.Context, filter repository.SubscriptionFilter) (page *repository.SubscriptionPage, err error) {
+	began := time.Now()
+	defer func() { r.observe("GetAll", began, err) }()
+	return r.next.GetAll(ctx, filter)
+}
+
+func (r *instrumentedRepository) GetByID(ctx context.Context, id int) (sub *models.Subscription, err error) {
+	began := time.Now()
+	defer func() { r.observe("GetByID", began, err) }()
+	return r.next.GetByID(ctx, id)
+}
+
+func (r *instrumentedRepository) Update(ctx context.Context, id int, sub *models.Subscription, version int) (err error) {
+	began := time.Now()
+	defer func() { r.observe("Update", began, err) }()
+	return r.next.Update(ctx, id, sub, version)
+}
+
+func (r *instrumentedRepository) Patch(ctx context.Context, id int, patch models.SubscriptionPatch, version int) (err error) {
+	began := time.Now()
+	defer func() { r.observe("Patch", began, err) }()
+	return r.next.Patch(ctx, id, patch, version)
+}
+
+func (r *instrumentedRepository) Delete(ctx context.Context, id int, version int) (err error) {
+	began := time.Now()
+	defer func() { r.observe("Delete", began, err) }()
+	return r.next.Delete(ctx, id, version)
+}
+
+func (r *instrumentedRepository) GetCosts(ctx context.Context, filter repository.CostFilter) (costs []repository.MonthlyCost, err error) {
+	began := time.Now()
+	defer func() { r.observe("GetCosts", began, err) }()
+	return r.next.GetCosts(ctx, filter)
+}
+
+func (r *instrumentedRepository) CountSubscriptions(ctx context.Context, filter repository.CostFilter) (counts []repository.SubscriptionCount, err error) {
+	began := time.Now()
+	defer func() { r.observe("CountSubscriptions", began, err) }()
+	return r.next.CountSubscriptions(ctx, filter)
+}
+
+func (r *instrumentedRepository) GetServiceStats(ctx context.Context, month string) (stats []repository.ServiceStats, err error) {
+	began := time.Now()
+	defer func() { r.observe("GetServiceStats", began, err) }()
+	return r.next.GetServiceStats(ctx, month)
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// APIKey — ключ доступа для вызовов от имени сервиса, а не пользователя.
+// Сам ключ не хранится: по нему считается хеш, а для опознания показываются первые символы
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // начало ключа, по которому его можно узнать в списке
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // nil — бессрочный ключ
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Hash       string     `json:"-"` // SHA-256 ключа в hex
+}
+
+// Active сообщает, что ключ не отозван и не истёк к моменту now
+func (k APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
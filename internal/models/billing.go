@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// BillingPeriod — единица периода оплаты подписки
+type BillingPeriod string
+
+const (
+	BillingWeekly    BillingPeriod = "weekly"
+	BillingMonthly   BillingPeriod = "monthly"
+	BillingQuarterly BillingPeriod = "quarterly"
+	BillingYearly    BillingPeriod = "yearly"
+)
+
+// daysPerMonth — средняя длина месяца в днях, по ней недельная цена приводится к месячной
+const daysPerMonth = 365.25 / 12
+
+// SetDefaults заполняет незаданные условия оплаты значениями по умолчанию: раз в месяц в рублях
+func (s *Subscription) SetDefaults() {
+	if s.Currency == "" {
+		s.Currency = BaseCurrency
+	}
+	if s.BillingPeriod == "" {
+		s.BillingPeriod = BillingMonthly
+	}
+	if s.BillingInterval == 0 {
+		s.BillingInterval = 1
+	}
+}
+
+// billingStep возвращает промежуток между списаниями: в днях для weekly и в месяцах для остальных периодов
+func (s Subscription) billingStep() (step int, days bool) {
+	interval := max(s.BillingInterval, 1)
+	switch s.BillingPeriod {
+	case BillingWeekly:
+		return 7 * interval, true
+	case BillingQuarterly:
+		return 3 * interval, false
+	case BillingYearly:
+		return 12 * interval, false
+	default:
+		return interval, false
+	}
+}
+
+// MonthlyEquivalent возвращает цену подписки в пересчёте на один месяц
+func (s Subscription) MonthlyEquivalent() float64 {
+	step, days := s.billingStep()
+	if days {
+		return float64(s.Price) * daysPerMonth / float64(step)
+	}
+	return float64(s.Price) / float64(step)
+}
+
+// ChargesBetween возвращает число списаний по подписке в месяцах с from по to включительно.
+// Первое списание приходится на первое число месяца start_date, следующие — через каждый период оплаты,
+// пока подписка активна. Подписка должна быть активна во всём диапазоне [from, to]
+func (s Subscription) ChargesBetween(from, to time.Time) int {
+	start, err := ParseMonth(s.StartDate)
+	if err != nil || to.Before(from) {
+		return 0
+	}
+	next := to.AddDate(0, 1, 0)
+	step, days := s.billingStep()
+	offset := func(t time.Time) int {
+		if days {
+			return int(t.Sub(start).Hours() / 24)
+		}
+		return MonthsBetween(start, t) - 1
+	}
+	// Число списаний до момента со смещением x от начала подписки — ceil(x / step)
+	before := func(x int) int { return (x + step - 1) / step }
+	return before(offset(next)) - before(offset(from))
+}
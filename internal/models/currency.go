@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// BaseCurrency — валюта, в которой задаются курсы: курс валюты — цена её единицы в рублях, как у ЦБ РФ
+const BaseCurrency = "RUB"
+
+// ExchangeRate — курс валюты, действующий с даты Date до следующего курса той же валюты
+type ExchangeRate struct {
+	Currency string    `json:"currency"` // код ISO 4217
+	Date     time.Time `json:"date"`     // дата начала действия курса, полночь UTC
+	Rate     float64   `json:"rate"`     // цена единицы валюты в рублях
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// minorPerUnit — число сотых долей (копеек, центов) в единице валюты
+const minorPerUnit = 100
+
+// Amount — денежная сумма в сотых долях единицы валюты (копейках, центах).
+// В JSON записывается числом в единицах валюты: 39999 — 399.99, 40000 — 400.
+// Поэтому целое число на входе, как и раньше, означает целые рубли
+type Amount int64
+
+// Money — сумма в валюте Currency (код ISO 4217)
+type Money struct {
+	Amount   Amount `json:"amount" swaggertype:"number" example:"399.99"`
+	Currency string `json:"currency" example:"RUB"`
+}
+
+// ErrAmountFormat — сумма не число или задана точнее сотых
+var ErrAmountFormat = errors.New("amount must be a decimal number with at most 2 decimal places")
+
+// Units возвращает сумму в n целых единиц валюты
+func Units(n int64) Amount {
+	return Amount(n * minorPerUnit)
+}
+
+// ParseAmount разбирает сумму в единицах валюты, например 400, 399.99 или 1.5e3.
+// Дробная часть точнее сотых не округляется, а считается ошибкой
+func ParseAmount(s string) (Amount, error) {
+	// big.Rat понимает и дроби вида 1/3, в денежных суммах они не нужны
+	if s == "" || strings.ContainsRune(s, '/') {
+		return 0, ErrAmountFormat
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, ErrAmountFormat
+	}
+	r.Mul(r, big.NewRat(minorPerUnit, 1))
+	if !r.IsInt() || !r.Num().IsInt64() {
+		return 0, ErrAmountFormat
+	}
+	return Amount(r.Num().Int64()), nil
+}
+
+// AmountFromFloat округляет сумму в единицах валюты до сотых
+func AmountFromFloat(f float64) Amount {
+	return Amount(math.Round(f * minorPerUnit))
+}
+
+// Float64 возвращает сумму в единицах валюты
+func (a Amount) Float64() float64 {
+	return float64(a) / minorPerUnit
+}
+
+// String записывает сумму в единицах валюты без лишних нулей: 400, 399.9, 399.99
+func (a Amount) String() string {
+	sign := ""
+	n := int64(a)
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	units, minor := n/minorPerUnit, n%minorPerUnit
+	if minor == 0 {
+		return sign + strconv.FormatInt(units, 10)
+	}
+	return sign + strconv.FormatInt(units, 10) + "." + strings.TrimRight(strconv.FormatInt(100+minor, 10)[1:], "0")
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	v, err := ParseAmount(string(data))
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, data)
+	}
+	*a = v
+	return nil
+}
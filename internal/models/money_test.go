@@ -0,0 +1,54 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+		ok   bool
+	}{
+		{"400", 40000, true},
+		{"399.99", 39999, true},
+		{"399.9", 39990, true},
+		{"1.5e3", 150000, true},
+		{"-0.01", -1, true},
+		{"399.999", 0, false},
+		{"1/3", 0, false},
+		{"abc", 0, false},
+		{"", 0, false},
+		{"1e30", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseAmount(tt.in)
+			if !tt.ok {
+				assert.ErrorIs(t, err, ErrAmountFormat)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAmount_JSON(t *testing.T) {
+	data, err := json.Marshal(Money{Amount: 39999, Currency: "RUB"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount": 399.99, "currency": "RUB"}`, string(data))
+
+	for amount, want := range map[Amount]string{40000: "400", 39990: "399.9", 5: "0.05", -150: "-1.5"} {
+		assert.Equal(t, want, amount.String())
+	}
+
+	var m Money
+	require.NoError(t, json.Unmarshal([]byte(`{"amount": 400, "currency": "USD"}`), &m))
+	assert.Equal(t, Units(400), m.Amount)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"amount": 0.001}`), &m), ErrAmountFormat)
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// MonthLayout — формат месяца и года (MM-YYYY), в котором даты подписок передаются через API
+const MonthLayout = "01-2006"
+
+// ParseMonth разбирает строку MM-YYYY и возвращает первое число месяца в UTC
+func ParseMonth(s string) (time.Time, error) {
+	t, err := time.Parse(MonthLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid month %q, expected MM-YYYY", s)
+	}
+	return t, nil
+}
+
+// MonthsBetween возвращает количество месяцев в диапазоне [from, to] включительно.
+// Для пустого диапазона (to раньше from) возвращается 0
+func MonthsBetween(from, to time.Time) int {
+	n := (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month()) + 1
+	if n < 0 {
+		return 0
+	}
+	return n
+}
@@ -1,15 +1,77 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
 type Subscription struct {
 	ID          int       `json:"id" db:"id"`
-	ServiceName string    `json:"service_name" db:"service_name"`
-	Price       int       `json:"price" db:"price"`
-	UserID      uuid.UUID `json:"user_id" db:"user_id"`
-	StartDate   string    `json:"start_date" db:"start_date"`       // MM-YYYY
-	EndDate     *string   `json:"end_date,omitempty" db:"end_date"` // nullable
-	//  CHECK (start_date ~ '^\d{2}-\d{4})
+	ServiceName string    `json:"service_name" db:"service_name" binding:"required,service_name"`
+	Price       Amount    `json:"price" db:"price" binding:"price" swaggertype:"number" example:"399.99"` // стоимость одного списания в валюте Currency
+	UserID      uuid.UUID `json:"user_id" db:"user_id" binding:"required"`
+	StartDate   string    `json:"start_date" db:"start_date" binding:"required,month"`        // MM-YYYY, в БД хранится как DATE (первое число месяца)
+	EndDate     *string   `json:"end_date,omitempty" db:"end_date" binding:"omitempty,month"` // MM-YYYY, nullable, не раньше start_date
+	// Списание происходит раз в BillingInterval периодов BillingPeriod, по умолчанию — раз в месяц
+	BillingPeriod   BillingPeriod `json:"billing_period" db:"billing_period" binding:"omitempty,billing_period" enums:"weekly,monthly,quarterly,yearly"`
+	BillingInterval int           `json:"billing_interval" db:"billing_interval" binding:"omitempty,billing_interval" minimum:"1"`
+	Currency        string        `json:"currency" db:"currency" binding:"omitempty,iso4217" example:"RUB"` // код ISO 4217, по умолчанию RUB
+	Version         int           `json:"version" db:"version"`                                             // увеличивается при каждом изменении, задаётся хранилищем
+	CreatedAt       time.Time     `json:"created_at" db:"created_at"`                                       // задаётся хранилищем
+	UpdatedAt       time.Time     `json:"updated_at" db:"updated_at"`                                       // время последнего изменения, задаётся хранилищем
+}
+
+// SubscriptionPatch — частичное изменение подписки. Поля со значением nil не изменяются,
+// ClearEndDate явно сбрасывает end_date в NULL (подписка снова становится бессрочной)
+type SubscriptionPatch struct {
+	ServiceName     *string
+	Price           *Amount
+	UserID          *uuid.UUID
+	StartDate       *string
+	EndDate         *string
+	ClearEndDate    bool
+	BillingPeriod   *BillingPeriod
+	BillingInterval *int
+	Currency        *string
+}
+
+// IsEmpty сообщает, что патч ничего не меняет
+func (p SubscriptionPatch) IsEmpty() bool {
+	return p.ServiceName == nil && p.Price == nil && p.UserID == nil &&
+		p.StartDate == nil && p.EndDate == nil && !p.ClearEndDate &&
+		p.BillingPeriod == nil && p.BillingInterval == nil && p.Currency == nil
+}
+
+// DiffSubscriptions возвращает патч, содержащий только поля, которые отличаются в to по сравнению с from
+func DiffSubscriptions(from, to Subscription) SubscriptionPatch {
+	var p SubscriptionPatch
+	if from.ServiceName != to.ServiceName {
+		p.ServiceName = &to.ServiceName
+	}
+	if from.Price != to.Price {
+		p.Price = &to.Price
+	}
+	if from.UserID != to.UserID {
+		p.UserID = &to.UserID
+	}
+	if from.StartDate != to.StartDate {
+		p.StartDate = &to.StartDate
+	}
+	switch {
+	case to.EndDate == nil && from.EndDate != nil:
+		p.ClearEndDate = true
+	case to.EndDate != nil && (from.EndDate == nil || *from.EndDate != *to.EndDate):
+		p.EndDate = to.EndDate
+	}
+	if from.BillingPeriod != to.BillingPeriod {
+		p.BillingPeriod = &to.BillingPeriod
+	}
+	if from.BillingInterval != to.BillingInterval {
+		p.BillingInterval = &to.BillingInterval
+	}
+	if from.Currency != to.Currency {
+		p.Currency = &to.Currency
+	}
+	return p
 }
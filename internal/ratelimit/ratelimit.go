@@ -0,0 +1,102 @@
+// Package ratelimit ограничивает частоту запросов алгоритмом token bucket
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit — параметры бакета: Rate токенов в секунду в среднем и не больше Burst запросов подряд
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result — итог списания токена
+type Result struct {
+	Allowed    bool
+	Limit      int           // ёмкость бакета
+	Remaining  int           // сколько запросов ещё можно сделать подряд
+	RetryAfter time.Duration // через сколько появится токен, если запрос отклонён
+	Reset      time.Duration // через сколько бакет заполнится полностью
+}
+
+// Store хранит бакеты. MemoryStore подходит для одного экземпляра сервиса;
+// чтобы реплики делили общий бюджет, можно реализовать Store поверх общего хранилища, например Redis
+type Store interface {
+	// Take списывает токен из бакета key с параметрами limit
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// sweepInterval — как часто MemoryStore удаляет заполнившиеся бакеты
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // к этому моменту бакет заполнится и его можно удалить
+}
+
+// MemoryStore хранит бакеты в памяти процесса
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryStore() Store {
+	return newMemoryStore(time.Now)
+}
+
+func newMemoryStore(now func() time.Time) *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), now: now, lastSweep: now()}
+}
+
+// Take списывает токен из бакета key. Новый бакет создаётся полным
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	capacity := float64(limit.Burst)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+
+	res := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / limit.Rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((capacity - b.tokens) / limit.Rate)
+	b.full = now.Add(res.Reset)
+	return res, nil
+}
+
+// sweep удаляет заполнившиеся бакеты: они ничем не отличаются от новых
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := newMemoryStore(func() time.Time { return now })
+	limit := Limit{Rate: 2, Burst: 3}
+	ctx := context.Background()
+
+	for want := 2; want >= 0; want-- {
+		res, err := store.Take(ctx, "a", limit)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 3, res.Limit)
+		assert.Equal(t, want, res.Remaining)
+	}
+
+	res, _ := store.Take(ctx, "a", limit)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+	assert.Equal(t, 1500*time.Millisecond, res.Reset)
+
+	// Другой ключ — отдельный бакет
+	res, _ = store.Take(ctx, "b", limit)
+	assert.True(t, res.Allowed)
+
+	// За полсекунды набирается один токен
+	now = now.Add(500 * time.Millisecond)
+	res, _ = store.Take(ctx, "a", limit)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	// Заполнившиеся бакеты удаляются
+	now = now.Add(2 * time.Minute)
+	_, _ = store.Take(ctx, "c", limit)
+	assert.Len(t, store.buckets, 1)
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"rest-service/internal/models"
+	"time"
+)
+
+// APIKeyRepository — хранилище API-ключей.
+// Методы возвращают ErrNotFound, если ключа с указанным ID или хешем нет
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) (int, error)
+	List(ctx context.Context) ([]models.APIKey, error)
+	GetByID(ctx context.Context, id int) (*models.APIKey, error)
+	GetByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	// Rotate заменяет секрет действующего ключа; для отозванного или истёкшего к моменту now ключа возвращает ErrNotFound
+	Rotate(ctx context.Context, id int, prefix, hash string, now time.Time) error
+	// Revoke отзывает ключ. Повторный отзыв не меняет время первого
+	Revoke(ctx context.Context, id int, at time.Time) error
+	TouchLastUsed(ctx context.Context, id int, at time.Time) error
+}
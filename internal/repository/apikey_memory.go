@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"rest-service/internal/models"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryAPIKeyRepository хранит API-ключи в памяти процесса
+type MemoryAPIKeyRepository struct {
+	mu     sync.RWMutex
+	nextID int
+	keys   map[int]models.APIKey
+}
+
+func NewMemoryAPIKeyRepository() APIKeyRepository {
+	return &MemoryAPIKeyRepository{nextID: 1, keys: make(map[int]models.APIKey)}
+}
+
+// cloneAPIKey копирует ключ вместе со срезом и указателями, чтобы вызывающий код не менял хранимые данные
+func cloneAPIKey(key models.APIKey) models.APIKey {
+	key.Scopes = slices.Clone(key.Scopes)
+	for _, t := range []**time.Time{&key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt} {
+		if *t != nil {
+			v := **t
+			*t = &v
+		}
+	}
+	return key
+}
+
+// Create сохраняет новый ключ и заполняет его ID и время создания
+func (r *MemoryAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, k := range r.keys {
+		if k.Hash == key.Hash {
+			return 0, fmt.Errorf("%w: api key hash already exists", ErrConflict)
+		}
+	}
+	key.ID = r.nextID
+	key.CreatedAt = time.Now().UTC()
+	r.nextID++
+	r.keys[key.ID] = cloneAPIKey(*key)
+	return key.ID, nil
+}
+
+// List возвращает все ключи, включая отозванные, в порядке создания
+func (r *MemoryAPIKeyRepository) List(ctx context.Context) ([]models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]models.APIKey, 0, len(r.keys))
+	for _, k := range r.keys {
+		keys = append(keys, cloneAPIKey(k))
+	}
+	slices.SortFunc(keys, func(a, b models.APIKey) int { return a.ID - b.ID })
+	return keys, nil
+}
+
+// GetByID возвращает ключ по ID
+func (r *MemoryAPIKeyRepository) GetByID(ctx context.Context, id int) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	k, ok := r.keys[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	k = cloneAPIKey(k)
+	return &k, nil
+}
+
+// GetByHash возвращает ключ по SHA-256 его значения
+func (r *MemoryAPIKeyRepository) GetByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, k := range r.keys {
+		if k.Hash == hash {
+			k = cloneAPIKey(k)
+			return &k, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Rotate заменяет секрет действующего ключа
+func (r *MemoryAPIKeyRepository) Rotate(ctx context.Context, id int, prefix, hash string, now time.Time) error {
+	return r.update(id, func(k *models.APIKey) bool {
+		if k.RevokedAt != nil || (k.ExpiresAt != nil && !k.ExpiresAt.After(now)) {
+			return false
+		}
+		k.Prefix, k.Hash = prefix, hash
+		return true
+	})
+}
+
+// Revoke отзывает ключ
+func (r *MemoryAPIKeyRepository) Revoke(ctx context.Context, id int, at time.Time) error {
+	return r.update(id, func(k *models.APIKey) bool {
+		if k.RevokedAt == nil {
+			k.RevokedAt = &at
+		}
+		return true
+	})
+}
+
+// TouchLastUsed запоминает время последнего использования ключа
+func (r *MemoryAPIKeyRepository) TouchLastUsed(ctx context.Context, id int, at time.Time) error {
+	return r.update(id, func(k *models.APIKey) bool {
+		k.LastUsedAt = &at
+		return true
+	})
+}
+
+// update применяет fn к ключу с указанным ID. Если ключа нет или fn вернула false, возвращается ErrNotFound
+func (r *MemoryAPIKeyRepository) update(id int, fn func(k *models.APIKey) bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, ok := r.keys[id]
+	if !ok || !fn(&k) {
+		return ErrNotFound
+	}
+	r.keys[id] = k
+	return nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"rest-service/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PostgresAPIKeyRepository хранит API-ключи в PostgreSQL
+type PostgresAPIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &PostgresAPIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at, revoked_at`
+
+func scanPostgresAPIKey(row rowScanner) (*models.APIKey, error) {
+	var key models.APIKey
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.Hash, pq.Array(&key.Scopes),
+		&expiresAt, &lastUsedAt, &key.CreatedAt, &revokedAt)
+	if err != nil {
+		return nil, mapPostgresError(err)
+	}
+	key.ExpiresAt, key.LastUsedAt, key.RevokedAt = timePtr(expiresAt), timePtr(lastUsedAt), timePtr(revokedAt)
+	return &key, nil
+}
+
+// timePtr переводит nullable время из БД в *time.Time
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// Create сохраняет новый ключ и заполняет его ID и время создания
+func (r *PostgresAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) (_ int, err error) {
+	query := `INSERT INTO api_keys (name, prefix, key_hash, scopes, expires_at)
+              VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	ctx, span := startTableSpan(ctx, "api_keys", "PostgresAPIKeyRepository.Create", "INSERT", query)
+	defer func() { span.end(err) }()
+
+	err = r.db.QueryRowContext(ctx, query, key.Name, key.Prefix, key.Hash, pq.Array(key.Scopes), key.ExpiresAt).
+		Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return 0, mapPostgresError(err)
+	}
+	span.affectedRows(1)
+	return key.ID, nil
+}
+
+// List возвращает все ключи, включая отозванные, в порядке создания
+func (r *PostgresAPIKeyRepository) List(ctx context.Context) (keys []models.APIKey, err error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY id`
+	ctx, span := startTableSpan(ctx, "api_keys", "PostgresAPIKeyRepository.List", "SELECT", query)
+	defer func() { span.end(err) }()
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, mapPostgresError(err)
+	}
+	defer rows.Close()
+
+	keys = []models.APIKey{}
+	for rows.Next() {
+		key, err := scanPostgresAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, mapPostgresError(err)
+	}
+	span.returnedRows(len(keys))
+	return keys, nil
+}
+
+// GetByID возвращает ключ по ID
+func (r *PostgresAPIKeyRepository) GetByID(ctx context.Context, id int) (_ *models.APIKey, err error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1`
+	ctx, span := startTableSpan(ctx, "api_keys", "PostgresAPIKeyRepository.GetByID", "SELECT", query)
+	defer func() { span.end(err) }()
+
+	return scanPostgresAPIKey(r.db.QueryRowContext(ctx, query, id))
+}
+
+// GetByHash возвращает ключ по SHA-256 его значения
+func (r *PostgresAPIKeyRepository) GetByHash(ctx context.Context, hash string) (_ *models.APIKey, err error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1`
+	ctx, span := startTableSpan(ctx, "api_keys", "PostgresAPIKeyRepository.GetByHash", "SELECT", query)
+	defer func() { span.end(err) }()
+
+	return scanPostgresAPIKey(r.db.QueryRowContext(ctx, query, hash))
+}
+
+// Rotate заменяет секрет действующего ключа
+func (r *PostgresAPIKeyRepository) Rotate(ctx context.Context, id int, prefix, hash string, now time.Time) error {
+	query := `UPDATE api_keys SET prefix = $1, key_hash = $2
+              WHERE id = $3 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > $4)`
+	return r.execOne(ctx, "PostgresAPIKeyRepository.Rotate", query, prefix, hash, id, now)
+}
+
+// Revoke отзывает ключ
+func (r *PostgresAPIKeyRepository) Revoke(ctx context.Context, id int, at time.Time) error {
+	query := `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $1) WHERE id = $2`
+	return r.execOne(ctx, "PostgresAPIKeyRepository.Revoke", query, at, id)
+}
+
+// TouchLastUsed запоминает время последнего использования ключа
+func (r *PostgresAPIKeyRepository) TouchLastUsed(ctx context.Context, id int, at time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $1 WHERE id = $2`
+	return r.execOne(ctx, "PostgresAPIKeyRepository.TouchLastUsed", query, at, id)
+}
+
+// execOne выполняет UPDATE одного ключа и возвращает ErrNotFound, если запрос не затронул ни одной строки
+func (r *PostgresAPIKeyRepository) execOne(ctx context.Context, spanName, query string, args ...interface{}) (err error) {
+	ctx, span := startTableSpan(ctx, "api_keys", spanName, "UPDATE", query)
+	defer func() { span.end(err) }()
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return mapPostgresError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return mapPostgresError(err)
+	}
+	span.affectedRows(affected)
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"rest-service/internal/models"
+	"strings"
+	"time"
+)
+
+// SQLiteAPIKeyRepository хранит API-ключи в SQLite.
+// Скоупы хранятся строкой через пробел, время — текстом RFC 3339 в UTC (см. sqliteTime)
+type SQLiteAPIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &SQLiteAPIKeyRepository{db: db}
+}
+
+func scanSQLiteAPIKey(row rowScanner) (*models.APIKey, error) {
+	var key models.APIKey
+	var scopes string
+	var expiresAt, lastUsedAt, createdAt, revokedAt sql.NullString
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.Hash, &scopes, &expiresAt, &lastUsedAt, &createdAt, &revokedAt)
+	if err != nil {
+		return nil, mapSQLiteError(err)
+	}
+	key.Scopes = strings.Fields(scopes)
+
+	times := []struct {
+		src sql.NullString
+		dst **time.Time
+	}{{expiresAt, &key.ExpiresAt}, {lastUsedAt, &key.LastUsedAt}, {revokedAt, &key.RevokedAt}}
+	for _, t := range times {
+		if *t.dst, err = parseSQLiteTime(t.src); err != nil {
+			return nil, err
+		}
+	}
+	created, err := parseSQLiteTime(createdAt)
+	if err != nil {
+		return nil, err
+	}
+	key.CreatedAt = *created
+	return &key, nil
+}
+
+// Create сохраняет новый ключ и заполняет его ID и время создания
+func (r *SQLiteAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) (int, error) {
+	createdAt := time.Now().UTC()
+	query := `INSERT INTO api_keys (name, prefix, key_hash, scopes, expires_at, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	err := r.db.QueryRowContext(ctx, query, key.Name, key.Prefix, key.Hash, strings.Join(key.Scopes, " "),
+		sqliteTimePtr(key.ExpiresAt), sqliteTime(createdAt)).Scan(&key.ID)
+	if err != nil {
+		return 0, mapSQLiteError(err)
+	}
+	key.CreatedAt = createdAt
+	return key.ID, nil
+}
+
+// List возвращает все ключи, включая отозванные, в порядке создания
+func (r *SQLiteAPIKeyRepository) List(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, mapSQLiteError(err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanSQLiteAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, mapSQLiteError(rows.Err())
+}
+
+// GetByID возвращает ключ по ID
+func (r *SQLiteAPIKeyRepository) GetByID(ctx context.Context, id int) (*models.APIKey, error) {
+	return scanSQLiteAPIKey(r.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, id))
+}
+
+// GetByHash возвращает ключ по SHA-256 его значения
+func (r *SQLiteAPIKeyRepository) GetByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	return scanSQLiteAPIKey(r.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, hash))
+}
+
+// Rotate заменяет секрет действующего ключа
+func (r *SQLiteAPIKeyRepository) Rotate(ctx context.Context, id int, prefix, hash string, now time.Time) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE api_keys SET prefix = $1, key_hash = $2
+        WHERE id = $3 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > $4)`,
+		prefix, hash, id, sqliteTime(now))
+	return checkSQLiteAffected(result, err)
+}
+
+// Revoke отзывает ключ
+func (r *SQLiteAPIKeyRepository) Revoke(ctx context.Context, id int, at time.Time) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $1) WHERE id = $2`,
+		sqliteTime(at), id)
+	return checkSQLiteAffected(result, err)
+}
+
+// TouchLastUsed запоминает время последнего использования ключа
+func (r *SQLiteAPIKeyRepository) TouchLastUsed(ctx context.Context, id int, at time.Time) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = $1 WHERE id = $2`, sqliteTime(at), id)
+	return checkSQLiteAffected(result, err)
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rest-service/internal/models"
+)
+
+// testAPIKeyRepositorySuite проверяет поведение реализации APIKeyRepository на пустом хранилище
+func testAPIKeyRepositorySuite(t *testing.T, repo APIKeyRepository) {
+	ctx := context.Background()
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	key := &models.APIKey{Name: "billing", Prefix: "rsk_aaaa", Hash: "hash-1",
+		Scopes: []string{"subscriptions:read", "reports:read"}, ExpiresAt: &expires}
+	id, err := repo.Create(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+	assert.False(t, key.CreatedAt.IsZero())
+
+	_, err = repo.Create(ctx, &models.APIKey{Name: "dup", Prefix: "rsk_aaaa", Hash: "hash-1", Scopes: []string{"admin"}})
+	assert.ErrorIs(t, err, ErrConflict)
+
+	got, err := repo.GetByHash(ctx, "hash-1")
+	require.NoError(t, err)
+	assert.Equal(t, "billing", got.Name)
+	assert.Equal(t, []string{"subscriptions:read", "reports:read"}, got.Scopes)
+	assert.True(t, expires.Equal(*got.ExpiresAt))
+	assert.Nil(t, got.LastUsedAt)
+	assert.Nil(t, got.RevokedAt)
+	_, err = repo.GetByHash(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	used := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.TouchLastUsed(ctx, id, used))
+	// Истёкший ключ не перевыпускается
+	assert.ErrorIs(t, repo.Rotate(ctx, id, "rsk_bbbb", "hash-2", expires), ErrNotFound)
+	require.NoError(t, repo.Rotate(ctx, id, "rsk_bbbb", "hash-2", used))
+	_, err = repo.GetByHash(ctx, "hash-1")
+	assert.ErrorIs(t, err, ErrNotFound)
+	got, err = repo.GetByID(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "rsk_bbbb", got.Prefix)
+	assert.True(t, used.Equal(*got.LastUsedAt))
+
+	revoked := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.Revoke(ctx, id, revoked))
+	require.NoError(t, repo.Revoke(ctx, id, revoked.Add(time.Hour)))
+	got, _ = repo.GetByID(ctx, id)
+	assert.True(t, revoked.Equal(*got.RevokedAt))
+	assert.ErrorIs(t, repo.Rotate(ctx, id, "rsk_cccc", "hash-3", used), ErrNotFound)
+
+	assert.ErrorIs(t, repo.Revoke(ctx, 42, revoked), ErrNotFound)
+	_, err = repo.GetByID(ctx, 42)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = repo.Create(ctx, &models.APIKey{Name: "analytics", Prefix: "rsk_dddd", Hash: "hash-4", Scopes: []string{"reports:read"}})
+	require.NoError(t, err)
+	keys, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "billing", keys[0].Name)
+	assert.Equal(t, "analytics", keys[1].Name)
+	assert.Nil(t, keys[1].ExpiresAt)
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Доменные ошибки хранилища. Реализации SubscriptionRepository оборачивают в них
+// ошибки драйвера, чтобы обработчики могли выбрать ответ, не разбирая текст ошибок БД
+var (
+	ErrNotFound        = errors.New("subscription not found")
+	ErrConflict        = errors.New("conflict with existing data")
+	ErrValidation      = errors.New("data violates storage constraints")
+	ErrUnavailable     = errors.New("storage unavailable")
+	ErrVersionMismatch = errors.New("subscription version does not match")
+)
+
+// mapPostgresError переводит ошибку драйвера PostgreSQL в доменную ошибку.
+// Исходная ошибка сохраняется в цепочке для логирования
+func mapPostgresError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		code := string(pqErr.Code)
+		switch {
+		case code == "23505": // unique_violation
+			return fmt.Errorf("%w: %w", ErrConflict, err)
+		case code == "23514", code == "23502", // check_violation, not_null_violation
+			strings.HasPrefix(code, "22"): // data_exception: некорректные даты, переполнение и т.п.
+			return fmt.Errorf("%w: %w", ErrValidation, err)
+		case strings.HasPrefix(code, "08"), // connection_exception
+			strings.HasPrefix(code, "53"), // insufficient_resources
+			strings.HasPrefix(code, "57"): // operator_intervention: остановка сервера, отмена запроса
+			return fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return err
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"rest-service/internal/models"
+	"strings"
+	"time"
+)
+
+// exchangeRateDateLayout — формат даты начала действия курса в запросах к хранилищу
+const exchangeRateDateLayout = "2006-01-02"
+
+// ExchangeRateRepository — хранилище курсов валют к рублю
+type ExchangeRateRepository interface {
+	// Upsert сохраняет курсы, заменяя уже сохранённые курсы тех же валют на те же даты. Курсы сохраняются все или ни одного
+	Upsert(ctx context.Context, rates []models.ExchangeRate) error
+	// List возвращает курсы валюты currency (всех валют, если currency пустая) по возрастанию даты
+	List(ctx context.Context, currency string) ([]models.ExchangeRate, error)
+	// ListRange возвращает курсы валют currencies, нужные для пересчёта на даты из [from, to]:
+	// вступившие в силу в этом промежутке и последний курс каждой валюты до from. Порядок — как у List
+	ListRange(ctx context.Context, currencies []string, from, to time.Time) ([]models.ExchangeRate, error)
+}
+
+// rateRangeConditions строит условия WHERE для ListRange. cast дописывается к параметрам-датам
+func rateRangeConditions(currencies []string, from, to time.Time, cast string, args *queryArgs) []string {
+	placeholders := make([]string, len(currencies))
+	for i, currency := range currencies {
+		placeholders[i] = args.add(currency)
+	}
+	fromArg := args.add(from.Format(exchangeRateDateLayout)) + cast
+	toArg := args.add(to.Format(exchangeRateDateLayout)) + cast
+	return []string{
+		"currency IN (" + strings.Join(placeholders, ", ") + ")",
+		"effective_date <= " + toArg,
+		// Курс, вступивший в силу последним до from, ещё действует в начале промежутка
+		"effective_date >= COALESCE((SELECT MAX(prev.effective_date) FROM exchange_rates prev " +
+			"WHERE prev.currency = exchange_rates.currency AND prev.effective_date <= " + fromArg + "), " + fromArg + ")",
+	}
+}
+
+// parseRateDate разбирает дату курса, прочитанную из БД
+func parseRateDate(s string) (time.Time, error) {
+	return time.Parse(exchangeRateDateLayout, s)
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"rest-service/internal/models"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryExchangeRateRepository хранит курсы валют в памяти процесса
+type MemoryExchangeRateRepository struct {
+	mu    sync.RWMutex
+	rates map[string]map[time.Time]float64 // валюта → дата → курс
+}
+
+func NewMemoryExchangeRateRepository() ExchangeRateRepository {
+	return &MemoryExchangeRateRepository{rates: make(map[string]map[time.Time]float64)}
+}
+
+// Upsert сохраняет курсы
+func (r *MemoryExchangeRateRepository) Upsert(ctx context.Context, rates []models.ExchangeRate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rate := range rates {
+		byDate, ok := r.rates[rate.Currency]
+		if !ok {
+			byDate = make(map[time.Time]float64)
+			r.rates[rate.Currency] = byDate
+		}
+		// Как и в БД, хранится только дата
+		y, m, d := rate.Date.Date()
+		byDate[time.Date(y, m, d, 0, 0, 0, 0, time.UTC)] = rate.Rate
+	}
+	return nil
+}
+
+// List возвращает курсы валюты по возрастанию даты
+func (r *MemoryExchangeRateRepository) List(ctx context.Context, currency string) ([]models.ExchangeRate, error) {
+	r.mu.RLock()
+	rates := []models.ExchangeRate{}
+	for cur, byDate := range r.rates {
+		if currency != "" && cur != currency {
+			continue
+		}
+		for date, rate := range byDate {
+			rates = append(rates, models.ExchangeRate{Currency: cur, Date: date, Rate: rate})
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].Currency != rates[j].Currency {
+			return rates[i].Currency < rates[j].Currency
+		}
+		return rates[i].Date.Before(rates[j].Date)
+	})
+	return rates, nil
+}
+
+// ListRange возвращает курсы валют, действующие в промежутке [from, to]
+func (r *MemoryExchangeRateRepository) ListRange(ctx context.Context, currencies []string, from, to time.Time) ([]models.ExchangeRate, error) {
+	all, err := r.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	rates := []models.ExchangeRate{}
+	for i, rate := range all {
+		if !slices.Contains(currencies, rate.Currency) || rate.Date.After(to) {
+			continue
+		}
+		// Из курсов до from нужен только последний: следующий курс той же валюты вступил в силу позже from
+		if !rate.Date.After(fromDay) && i+1 < len(all) && all[i+1].Currency == rate.Currency && !all[i+1].Date.After(fromDay) {
+			continue
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"rest-service/internal/models"
+	"time"
+)
+
+// PostgresExchangeRateRepository хранит курсы валют в PostgreSQL
+type PostgresExchangeRateRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresExchangeRateRepository(db *sql.DB) ExchangeRateRepository {
+	return &PostgresExchangeRateRepository{db: db}
+}
+
+// Upsert сохраняет курсы в одной транзакции
+func (r *PostgresExchangeRateRepository) Upsert(ctx context.Context, rates []models.ExchangeRate) (err error) {
+	query := `INSERT INTO exchange_rates (currency, effective_date, rate) VALUES ($1, $2::DATE, $3)
+              ON CONFLICT (currency, effective_date) DO UPDATE SET rate = EXCLUDED.rate`
+	ctx, span := startTableSpan(ctx, "exchange_rates", "PostgresExchangeRateRepository.Upsert", "INSERT", query)
+	defer func() { span.end(err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return mapPostgresError(err)
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return mapPostgresError(err)
+	}
+	defer stmt.Close()
+	for _, rate := range rates {
+		if _, err = stmt.ExecContext(ctx, rate.Currency, rate.Date.Format(exchangeRateDateLayout), rate.Rate); err != nil {
+			return mapPostgresError(err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return mapPostgresError(err)
+	}
+	span.affectedRows(int64(len(rates)))
+	return nil
+}
+
+// List возвращает курсы валюты по возрастанию даты
+func (r *PostgresExchangeRateRepository) List(ctx context.Context, currency string) (rates []models.ExchangeRate, err error) {
+	var args queryArgs
+	var conds []string
+	if currency != "" {
+		conds = append(conds, "currency = "+args.add(currency))
+	}
+	query := `SELECT currency, TO_CHAR(effective_date, 'YYYY-MM-DD'), rate FROM exchange_rates` + whereClause(conds) +
+		` ORDER BY currency, effective_date`
+	ctx, span := startTableSpan(ctx, "exchange_rates", "PostgresExchangeRateRepository.List", "SELECT", query)
+	defer func() { span.end(err) }()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, mapPostgresError(err)
+	}
+	defer rows.Close()
+	rates, err = scanExchangeRates(rows, mapPostgresError)
+	span.returnedRows(len(rates))
+	return rates, err
+}
+
+// ListRange возвращает курсы валют, действующие в промежутке [from, to]
+func (r *PostgresExchangeRateRepository) ListRange(ctx context.Context, currencies []string, from, to time.Time) (rates []models.ExchangeRate, err error) {
+	if len(currencies) == 0 {
+		return []models.ExchangeRate{}, nil
+	}
+	var args queryArgs
+	query := `SELECT currency, TO_CHAR(effective_date, 'YYYY-MM-DD'), rate FROM exchange_rates` +
+		whereClause(rateRangeConditions(currencies, from, to, "::DATE", &args)) + ` ORDER BY currency, effective_date`
+	ctx, span := startTableSpan(ctx, "exchange_rates", "PostgresExchangeRateRepository.ListRange", "SELECT", query)
+	defer func() { span.end(err) }()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, mapPostgresError(err)
+	}
+	defer rows.Close()
+	rates, err = scanExchangeRates(rows, mapPostgresError)
+	span.returnedRows(len(rates))
+	return rates, err
+}
+
+// scanExchangeRates читает строки (currency, effective_date, rate) в срез курсов
+func scanExchangeRates(rows *sql.Rows, mapError func(error) error) ([]models.ExchangeRate, error) {
+	rates := []models.ExchangeRate{}
+	for rows.Next() {
+		var rate models.ExchangeRate
+		var date string
+		if err := rows.Scan(&rate.Currency, &date, &rate.Rate); err != nil {
+			return nil, mapError(err)
+		}
+		var err error
+		if rate.Date, err = parseRateDate(date); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, mapError(rows.Err())
+}
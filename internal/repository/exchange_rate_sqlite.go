@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"rest-service/internal/models"
+	"time"
+)
+
+// SQLiteExchangeRateRepository хранит курсы валют в SQLite, даты — текстом YYYY-MM-DD
+type SQLiteExchangeRateRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteExchangeRateRepository(db *sql.DB) ExchangeRateRepository {
+	return &SQLiteExchangeRateRepository{db: db}
+}
+
+// Upsert сохраняет курсы в одной транзакции
+func (r *SQLiteExchangeRateRepository) Upsert(ctx context.Context, rates []models.ExchangeRate) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return mapSQLiteError(err)
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO exchange_rates (currency, effective_date, rate) VALUES ($1, $2, $3)
+                                         ON CONFLICT (currency, effective_date) DO UPDATE SET rate = excluded.rate`)
+	if err != nil {
+		return mapSQLiteError(err)
+	}
+	defer stmt.Close()
+	for _, rate := range rates {
+		if _, err := stmt.ExecContext(ctx, rate.Currency, rate.Date.Format(exchangeRateDateLayout), rate.Rate); err != nil {
+			return mapSQLiteError(err)
+		}
+	}
+	return mapSQLiteError(tx.Commit())
+}
+
+// List возвращает курсы валюты по возрастанию даты
+func (r *SQLiteExchangeRateRepository) List(ctx context.Context, currency string) ([]models.ExchangeRate, error) {
+	var args queryArgs
+	var conds []string
+	if currency != "" {
+		conds = append(conds, "currency = "+args.add(currency))
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT currency, effective_date, rate FROM exchange_rates`+whereClause(conds)+
+		` ORDER BY currency, effective_date`, args...)
+	if err != nil {
+		return nil, mapSQLiteError(err)
+	}
+	defer rows.Close()
+	return scanExchangeRates(rows, mapSQLiteError)
+}
+
+// ListRange возвращает курсы валют, действующие в промежутке [from, to]. Даты YYYY-MM-DD сравниваются как строки
+func (r *SQLiteExchangeRateRepository) ListRange(ctx context.Context, currencies []string, from, to time.Time) ([]models.ExchangeRate, error) {
+	if len(currencies) == 0 {
+		return []models.ExchangeRate{}, nil
+	}
+	var args queryArgs
+	rows, err := r.db.QueryContext(ctx, `SELECT currency, effective_date, rate FROM exchange_rates`+
+		whereClause(rateRangeConditions(currencies, from, to, "", &args))+` ORDER BY currency, effective_date`, args...)
+	if err != nil {
+		return nil, mapSQLiteError(err)
+	}
+	defer rows.Close()
+	return scanExchangeRates(rows, mapSQLiteError)
+}
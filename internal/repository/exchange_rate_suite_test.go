@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rest-service/internal/models"
+)
+
+// testExchangeRateRepositorySuite проверяет поведение реализации ExchangeRateRepository на пустом хранилище
+func testExchangeRateRepositorySuite(t *testing.T, repo ExchangeRateRepository) {
+	ctx := context.Background()
+	date := func(month time.Month, day int) time.Time { return time.Date(2025, month, day, 0, 0, 0, 0, time.UTC) }
+
+	rates, err := repo.List(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, rates)
+
+	require.NoError(t, repo.Upsert(ctx, []models.ExchangeRate{
+		{Currency: "USD", Date: date(time.February, 1), Rate: 98.25},
+		{Currency: "USD", Date: date(time.January, 1), Rate: 100.5},
+		{Currency: "EUR", Date: date(time.January, 1), Rate: 105.125},
+	}))
+	// Курс на ту же дату заменяется
+	require.NoError(t, repo.Upsert(ctx, []models.ExchangeRate{{Currency: "USD", Date: date(time.February, 1), Rate: 97}}))
+
+	rates, err = repo.List(ctx, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, []models.ExchangeRate{
+		{Currency: "USD", Date: date(time.January, 1), Rate: 100.5},
+		{Currency: "USD", Date: date(time.February, 1), Rate: 97},
+	}, rates)
+
+	rates, err = repo.List(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, rates, 3)
+	assert.Equal(t, "EUR", rates[0].Currency)
+
+	rates, err = repo.List(ctx, "GBP")
+	require.NoError(t, err)
+	assert.Empty(t, rates)
+
+	// Для промежутка нужны курсы внутри него и последний курс до его начала, но не вся история
+	require.NoError(t, repo.Upsert(ctx, []models.ExchangeRate{
+		{Currency: "USD", Date: date(time.March, 1), Rate: 95},
+		{Currency: "USD", Date: date(time.April, 1), Rate: 94},
+	}))
+	rates, err = repo.ListRange(ctx, []string{"EUR", "USD"}, date(time.February, 15), date(time.March, 1))
+	require.NoError(t, err)
+	assert.Equal(t, []models.ExchangeRate{
+		{Currency: "EUR", Date: date(time.January, 1), Rate: 105.125},
+		{Currency: "USD", Date: date(time.February, 1), Rate: 97},
+		{Currency: "USD", Date: date(time.March, 1), Rate: 95},
+	}, rates)
+
+	rates, err = repo.ListRange(ctx, []string{"USD"}, date(time.March, 1), date(time.March, 1))
+	require.NoError(t, err)
+	assert.Equal(t, []models.ExchangeRate{{Currency: "USD", Date: date(time.March, 1), Rate: 95}}, rates)
+
+	rates, err = repo.ListRange(ctx, []string{"GBP"}, date(time.January, 1), date(time.December, 1))
+	require.NoError(t, err)
+	assert.Empty(t, rates)
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"rest-service/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultLimit — размер страницы, если клиент не указал limit
+	DefaultLimit = 100
+	// MaxLimit — максимальный размер страницы
+	MaxLimit = 1000
+
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// SortColumns — колонки, по которым разрешена сортировка списка подписок
+var SortColumns = []string{"id", "service_name", "price", "user_id", "start_date", "end_date"}
+
+// ErrInvalidCursor возвращается, если курсор не удалось разобрать
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SubscriptionFilter описывает фильтрацию, сортировку и пагинацию списка подписок
+type SubscriptionFilter struct {
+	UserID            uuid.UUID
+	ServiceName       string // точное совпадение
+	ServiceNamePrefix string // совпадение по префиксу
+	MinPrice          *models.Amount
+	MaxPrice          *models.Amount
+	ActiveIn          string    // MM-YYYY, подписка активна в этом месяце
+	CreatedSince      time.Time // создана не раньше; нулевое время — без фильтра
+	UpdatedSince      time.Time // изменена не раньше, для инкрементальной синхронизации
+
+	Sort  string // одна из SortColumns, по умолчанию id
+	Order string // asc или desc, по умолчанию asc
+
+	Limit  int
+	Offset int    // при наличии курсора игнорируется
+	Cursor string // непрозрачный курсор keyset-пагинации
+}
+
+// SubscriptionPage — страница списка подписок
+type SubscriptionPage struct {
+	Items      []models.Subscription
+	Total      int    // количество подписок, подходящих под фильтры, без учёта пагинации
+	NextCursor string // курсор следующей страницы, пустой если страница последняя
+}
+
+// Normalize проверяет фильтр и подставляет значения по умолчанию
+func (f *SubscriptionFilter) Normalize() error {
+	if f.Sort == "" {
+		f.Sort = "id"
+	}
+	if !isSortColumn(f.Sort) {
+		return fmt.Errorf("sort must be one of: %s", strings.Join(SortColumns, ", "))
+	}
+	f.Order = strings.ToLower(f.Order)
+	if f.Order == "" {
+		f.Order = SortAsc
+	}
+	if f.Order != SortAsc && f.Order != SortDesc {
+		return fmt.Errorf("order must be %s or %s", SortAsc, SortDesc)
+	}
+	if f.Limit == 0 {
+		f.Limit = DefaultLimit
+	}
+	if f.Limit < 0 || f.Limit > MaxLimit {
+		return fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+	}
+	if f.Offset < 0 {
+		return errors.New("offset must not be negative")
+	}
+	if f.MinPrice != nil && f.MaxPrice != nil && *f.MinPrice > *f.MaxPrice {
+		return errors.New("min_price must not exceed max_price")
+	}
+	if f.ActiveIn != "" {
+		if _, err := models.ParseMonth(f.ActiveIn); err != nil {
+			return err
+		}
+	}
+	if f.Cursor != "" {
+		c, err := decodeCursor(f.Cursor)
+		if err != nil {
+			return err
+		}
+		if c.Sort != f.Sort || c.Order != f.Order {
+			return fmt.Errorf("%w: cursor was issued for a different sort order", ErrInvalidCursor)
+		}
+	}
+	return nil
+}
+
+func isSortColumn(col string) bool {
+	for _, c := range SortColumns {
+		if c == col {
+			return true
+		}
+	}
+	return false
+}
+
+// cursor — содержимое курсора: значение колонки сортировки и ID последней записи страницы
+type cursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func encodeCursor(f SubscriptionFilter, last models.Subscription) string {
+	data, _ := json.Marshal(cursor{Sort: f.Sort, Order: f.Order, Value: sortValue(last, f.Sort), ID: last.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// sortValue возвращает строковое значение колонки сортировки для курсора.
+// Для пустого end_date возвращается пустая строка (бессрочная подписка)
+func sortValue(sub models.Subscription, col string) string {
+	switch col {
+	case "service_name":
+		return sub.ServiceName
+	case "price":
+		return strconv.FormatInt(int64(sub.Price), 10)
+	case "user_id":
+		return sub.UserID.String()
+	case "start_date":
+		return sub.StartDate
+	case "end_date":
+		if sub.EndDate == nil {
+			return ""
+		}
+		return *sub.EndDate
+	default:
+		return strconv.Itoa(sub.ID)
+	}
+}
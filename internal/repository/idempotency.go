@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"time"
+)
+
+// IdempotencyRecord — запрос с ключом идемпотентности и его сохранённый ответ
+type IdempotencyRecord struct {
+	Key         string
+	Token       string // идентификатор резервирования, выданный Reserve запросу, который занял ключ
+	RequestHash string // SHA-256 метода, пути и тела запроса
+	Status      int    // 0 — запрос ещё выполняется
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+// IdempotencyRepository — хранилище ключей идемпотентности.
+// Запись живёт до ExpiresAt; истёкшая запись считается отсутствующей
+type IdempotencyRepository interface {
+	// Reserve атомарно создаёт запись о начале выполнения запроса с новым Token.
+	// Если действующая запись с этим ключом уже есть, возвращает её и created == false
+	Reserve(ctx context.Context, key, requestHash string, now, lockedUntil time.Time) (rec *IdempotencyRecord, created bool, err error)
+	// Complete сохраняет ответ на запрос, который будет повторяться до expiresAt.
+	// Если запись с этим ключом занята другим резервированием (token не совпадает), возвращает ErrNotFound
+	Complete(ctx context.Context, key, token string, status int, body []byte, expiresAt time.Time) error
+	// Release удаляет запись, чтобы запрос с этим ключом можно было выполнить заново.
+	// Запись другого резервирования не удаляется
+	Release(ctx context.Context, key, token string) error
+	// DeleteExpired удаляет истёкшие записи и возвращает их число
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// newReservationToken возвращает случайный идентификатор резервирования ключа
+func newReservationToken() string {
+	return rand.Text()
+}
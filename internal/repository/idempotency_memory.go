@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryIdempotencyRepository хранит ключи идемпотентности в памяти процесса
+type MemoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+}
+
+func NewMemoryIdempotencyRepository() IdempotencyRepository {
+	return &MemoryIdempotencyRepository{records: make(map[string]IdempotencyRecord)}
+}
+
+// Reserve создаёт запись или занимает истёкшую
+func (r *MemoryIdempotencyRepository) Reserve(ctx context.Context, key, requestHash string, now, lockedUntil time.Time) (*IdempotencyRecord, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rec, ok := r.records[key]; ok && rec.ExpiresAt.After(now) {
+		rec.Body = bytes.Clone(rec.Body)
+		return &rec, false, nil
+	}
+	rec := IdempotencyRecord{Key: key, Token: newReservationToken(), RequestHash: requestHash, ExpiresAt: lockedUntil}
+	r.records[key] = rec
+	return &rec, true, nil
+}
+
+// Complete сохраняет ответ на запрос, если запись всё ещё занята резервированием token
+func (r *MemoryIdempotencyRepository) Complete(ctx context.Context, key, token string, status int, body []byte, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.records[key]
+	if !ok || rec.Token != token {
+		return ErrNotFound
+	}
+	rec.Status, rec.Body, rec.ExpiresAt = status, bytes.Clone(body), expiresAt
+	r.records[key] = rec
+	return nil
+}
+
+// Release удаляет запись, если она всё ещё занята резервированием token
+func (r *MemoryIdempotencyRepository) Release(ctx context.Context, key, token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rec, ok := r.records[key]; !ok || rec.Token != token {
+		return ErrNotFound
+	}
+	delete(r.records, key)
+	return nil
+}
+
+// DeleteExpired удаляет истёкшие записи
+func (r *MemoryIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for key, rec := range r.records {
+		if !rec.ExpiresAt.After(now) {
+			delete(r.records, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
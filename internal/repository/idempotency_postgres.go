@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// PostgresIdempotencyRepository хранит ключи идемпотентности в PostgreSQL
+type PostgresIdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return &PostgresIdempotencyRepository{db: db}
+}
+
+// reserveAttempts — сколько раз Reserve повторяет попытку, если существующая запись исчезла между запросами
+const reserveAttempts = 3
+
+// Reserve создаёт запись или занимает истёкшую. Конкурентные вызовы с одним ключом
+// сериализуются на уникальном индексе, поэтому запись создаёт ровно один из них
+func (r *PostgresIdempotencyRepository) Reserve(ctx context.Context, key, requestHash string, now, lockedUntil time.Time) (rec *IdempotencyRecord, created bool, err error) {
+	query := `INSERT INTO idempotency_keys (key, token, request_hash, status, response, expires_at)
+              VALUES ($1, $2, $3, 0, NULL, $4)
+              ON CONFLICT (key) DO UPDATE
+                  SET token = EXCLUDED.token, request_hash = EXCLUDED.request_hash, status = 0, response = NULL,
+                      expires_at = EXCLUDED.expires_at
+                  WHERE idempotency_keys.expires_at <= $5
+              RETURNING key`
+	ctx, span := startTableSpan(ctx, "idempotency_keys", "PostgresIdempotencyRepository.Reserve", "INSERT", query)
+	defer func() { span.end(err) }()
+
+	for range reserveAttempts {
+		var inserted string
+		token := newReservationToken()
+		err = r.db.QueryRowContext(ctx, query, key, token, requestHash, lockedUntil, now).Scan(&inserted)
+		if err == nil {
+			return &IdempotencyRecord{Key: key, Token: token, RequestHash: requestHash, ExpiresAt: lockedUntil}, true, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, false, mapPostgresError(err)
+		}
+
+		// Действующая запись уже есть: возвращаем её
+		rec = &IdempotencyRecord{Key: key}
+		err = r.db.QueryRowContext(ctx, `SELECT token, request_hash, status, COALESCE(response, ''::BYTEA), expires_at
+                                         FROM idempotency_keys WHERE key = $1`, key).
+			Scan(&rec.Token, &rec.RequestHash, &rec.Status, &rec.Body, &rec.ExpiresAt)
+		if err == nil {
+			return rec, false, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, false, mapPostgresError(err)
+		}
+	}
+	return nil, false, ErrConflict
+}
+
+// Complete сохраняет ответ на запрос, если запись всё ещё занята резервированием token
+func (r *PostgresIdempotencyRepository) Complete(ctx context.Context, key, token string, status int, body []byte, expiresAt time.Time) error {
+	query := `UPDATE idempotency_keys SET status = $1, response = $2, expires_at = $3 WHERE key = $4 AND token = $5`
+	return r.execOne(ctx, "PostgresIdempotencyRepository.Complete", "UPDATE", query, status, body, expiresAt, key, token)
+}
+
+// Release удаляет запись, если она всё ещё занята резервированием token
+func (r *PostgresIdempotencyRepository) Release(ctx context.Context, key, token string) error {
+	query := `DELETE FROM idempotency_keys WHERE key = $1 AND token = $2`
+	return r.execOne(ctx, "PostgresIdempotencyRepository.Release", "DELETE", query, key, token)
+}
+
+// execOne выполняет UPDATE или DELETE одной записи и возвращает ErrNotFound, если запрос не затронул ни одной строки
+func (r *PostgresIdempotencyRepository) execOne(ctx context.Context, spanName, operation, query string, args ...interface{}) (err error) {
+	ctx, span := startTableSpan(ctx, "idempotency_keys", spanName, operation, query)
+	defer func() { span.end(err) }()
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return mapPostgresError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return mapPostgresError(err)
+	}
+	span.affectedRows(affected)
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteExpired удаляет истёкшие записи
+func (r *PostgresIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (deleted int64, err error) {
+	query := `DELETE FROM idempotency_keys WHERE expires_at <= $1`
+	ctx, span := startTableSpan(ctx, "idempotency_keys", "PostgresIdempotencyRepository.DeleteExpired", "DELETE", query)
+	defer func() { span.end(err) }()
+
+	result, err := r.db.ExecContext(ctx, query, now)
+	if err != nil {
+		return 0, mapPostgresError(err)
+	}
+	deleted, err = result.RowsAffected()
+	span.affectedRows(deleted)
+	return deleted, mapPostgresError(err)
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SQLiteIdempotencyRepository хранит ключи идемпотентности в SQLite
+type SQLiteIdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return &SQLiteIdempotencyRepository{db: db}
+}
+
+// Reserve создаёт запись или занимает истёкшую
+func (r *SQLiteIdempotencyRepository) Reserve(ctx context.Context, key, requestHash string, now, lockedUntil time.Time) (*IdempotencyRecord, bool, error) {
+	query := `INSERT INTO idempotency_keys (key, token, request_hash, status, response, expires_at)
+              VALUES ($1, $2, $3, 0, NULL, $4)
+              ON CONFLICT (key) DO UPDATE
+                  SET token = excluded.token, request_hash = excluded.request_hash, status = 0, response = NULL,
+                      expires_at = excluded.expires_at
+                  WHERE idempotency_keys.expires_at <= $5
+              RETURNING key`
+	for range reserveAttempts {
+		var inserted string
+		token := newReservationToken()
+		err := r.db.QueryRowContext(ctx, query, key, token, requestHash, sqliteTime(lockedUntil), sqliteTime(now)).Scan(&inserted)
+		if err == nil {
+			return &IdempotencyRecord{Key: key, Token: token, RequestHash: requestHash, ExpiresAt: lockedUntil}, true, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, false, mapSQLiteError(err)
+		}
+
+		rec := &IdempotencyRecord{Key: key}
+		var expiresAt sql.NullString
+		err = r.db.QueryRowContext(ctx, `SELECT token, request_hash, status, COALESCE(response, X''), expires_at
+                                         FROM idempotency_keys WHERE key = $1`, key).
+			Scan(&rec.Token, &rec.RequestHash, &rec.Status, &rec.Body, &expiresAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, false, mapSQLiteError(err)
+		}
+		t, err := parseSQLiteTime(expiresAt)
+		if err != nil {
+			return nil, false, err
+		}
+		rec.ExpiresAt = *t
+		return rec, false, nil
+	}
+	return nil, false, ErrConflict
+}
+
+// Complete сохраняет ответ на запрос, если запись всё ещё занята резервированием token
+func (r *SQLiteIdempotencyRepository) Complete(ctx context.Context, key, token string, status int, body []byte, expiresAt time.Time) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE idempotency_keys SET status = $1, response = $2, expires_at = $3 WHERE key = $4 AND token = $5`,
+		status, body, sqliteTime(expiresAt), key, token)
+	return checkSQLiteAffected(result, err)
+}
+
+// Release удаляет запись, если она всё ещё занята резервированием token
+func (r *SQLiteIdempotencyRepository) Release(ctx context.Context, key, token string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = $1 AND token = $2`, key, token)
+	return checkSQLiteAffected(result, err)
+}
+
+// DeleteExpired удаляет истёкшие записи
+func (r *SQLiteIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, sqliteTime(now))
+	if err != nil {
+		return 0, mapSQLiteError(err)
+	}
+	deleted, err := result.RowsAffected()
+	return deleted, mapSQLiteError(err)
+}
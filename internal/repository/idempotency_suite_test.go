@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testIdempotencyRepositorySuite проверяет поведение реализации IdempotencyRepository на пустом хранилище
+func testIdempotencyRepositorySuite(t *testing.T, repo IdempotencyRepository) {
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	locked := now.Add(time.Minute)
+
+	rec, created, err := repo.Reserve(ctx, "user:1:a", "hash-a", now, locked)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "hash-a", rec.RequestHash)
+	require.NotEmpty(t, rec.Token)
+	token := rec.Token
+
+	// Пока запрос выполняется, повтор видит незавершённую запись
+	rec, created, err = repo.Reserve(ctx, "user:1:a", "hash-b", now.Add(time.Second), locked)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "hash-a", rec.RequestHash)
+	assert.Zero(t, rec.Status)
+
+	assert.ErrorIs(t, repo.Complete(ctx, "user:1:a", "other", 201, []byte(`{"id":2}`), now.Add(time.Hour)), ErrNotFound)
+	require.NoError(t, repo.Complete(ctx, "user:1:a", token, 201, []byte(`{"id":1}`), now.Add(time.Hour)))
+	rec, created, err = repo.Reserve(ctx, "user:1:a", "hash-a", now.Add(2*time.Minute), now.Add(3*time.Minute))
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, 201, rec.Status)
+	assert.JSONEq(t, `{"id":1}`, string(rec.Body))
+	assert.True(t, rec.ExpiresAt.Equal(now.Add(time.Hour)))
+
+	// После истечения ключ можно занять заново
+	rec, created, err = repo.Reserve(ctx, "user:1:a", "hash-c", now.Add(time.Hour), now.Add(time.Hour+time.Minute))
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "hash-c", rec.RequestHash)
+	assert.NotEqual(t, token, rec.Token)
+
+	// Освобождённый ключ тоже можно занять заново
+	require.NoError(t, repo.Release(ctx, "user:1:a", rec.Token))
+	_, created, err = repo.Reserve(ctx, "user:1:a", "hash-d", now, locked)
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	// Блокировка истекла, пока первый запрос ещё выполнялся, и ключ занял повтор.
+	// Завершение и освобождение первого запроса не должны затронуть запись повтора
+	first, created, err := repo.Reserve(ctx, "user:1:slow", "hash", now, locked)
+	require.NoError(t, err)
+	require.True(t, created)
+	second, created, err := repo.Reserve(ctx, "user:1:slow", "hash", locked, locked.Add(time.Minute))
+	require.NoError(t, err)
+	require.True(t, created)
+	assert.ErrorIs(t, repo.Complete(ctx, "user:1:slow", first.Token, 201, []byte(`{"id":1}`), now.Add(time.Hour)), ErrNotFound)
+	assert.ErrorIs(t, repo.Release(ctx, "user:1:slow", first.Token), ErrNotFound)
+	rec, created, err = repo.Reserve(ctx, "user:1:slow", "hash", locked.Add(time.Second), locked.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, second.Token, rec.Token)
+	assert.Zero(t, rec.Status)
+	require.NoError(t, repo.Complete(ctx, "user:1:slow", second.Token, 201, []byte(`{"id":2}`), now.Add(time.Hour)))
+
+	// Из конкурентных запросов с одним ключом запись создаёт только один
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	winners := 0
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, created, err := repo.Reserve(ctx, "user:1:race", "hash", now, locked)
+			assert.NoError(t, err)
+			if created {
+				mu.Lock()
+				winners++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, winners)
+
+	deleted, err := repo.DeleteExpired(ctx, locked)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, deleted)
+	_, created, err = repo.Reserve(ctx, "user:1:race", "other", now, locked)
+	require.NoError(t, err)
+	assert.True(t, created)
+}
@@ -0,0 +1,519 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"rest-service/internal/models"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemorySubscriptionRepository хранит подписки в памяти процесса.
+// Повторяет семантику PostgresSubscriptionRepository и безопасна для конкурентного использования
+type MemorySubscriptionRepository struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]models.Subscription
+}
+
+func NewMemorySubscriptionRepository() SubscriptionRepository {
+	return &MemorySubscriptionRepository{nextID: 1, subs: make(map[int]models.Subscription)}
+}
+
+// GetCosts подсчитывает стоимость подписок по месяцам периода, группам и валютам, так же как GetCosts в PostgreSQL
+func (r *MemorySubscriptionRepository) GetCosts(ctx context.Context, filter CostFilter) ([]MonthlyCost, error) {
+	from, err := models.ParseMonth(filter.Start)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+	to, err := models.ParseMonth(filter.End)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+	if !filter.GroupBy.Valid() {
+		return nil, fmt.Errorf("%w: unknown cost group %q", ErrValidation, filter.GroupBy)
+	}
+
+	type group struct {
+		month    time.Time
+		key      string
+		currency string
+	}
+	charged := make(map[group]models.Amount)
+	normalized := make(map[group]float64)
+	active := make(map[group]int)
+	r.mu.RLock()
+	for _, sub := range r.subs {
+		if !matchesCostFilter(filter, sub) {
+			continue
+		}
+		key := costGroupKey(filter.GroupBy, sub)
+		subStart, subEnd := activePeriod(sub)
+		last := earlierOf(subEnd, to)
+		for month := laterOf(subStart, from); !month.After(last); month = month.AddDate(0, 1, 0) {
+			g := group{month, key, sub.Currency}
+			charged[g] += sub.Price * models.Amount(sub.ChargesBetween(month, month))
+			normalized[g] += sub.MonthlyEquivalent()
+			active[g]++
+		}
+	}
+	r.mu.RUnlock()
+
+	costs := make([]MonthlyCost, 0, len(normalized))
+	for g, n := range normalized {
+		costs = append(costs, MonthlyCost{
+			Month:      g.month,
+			Group:      g.key,
+			Charged:    models.Money{Amount: charged[g], Currency: g.currency},
+			Normalized: models.Money{Amount: models.Amount(math.Round(n)), Currency: g.currency},
+			Active:     active[g],
+		})
+	}
+	sort.Slice(costs, func(i, j int) bool {
+		if !costs[i].Month.Equal(costs[j].Month) {
+			return costs[i].Month.Before(costs[j].Month)
+		}
+		if costs[i].Group != costs[j].Group {
+			return costs[i].Group < costs[j].Group
+		}
+		return costs[i].Charged.Currency < costs[j].Charged.Currency
+	})
+	return costs, nil
+}
+
+// CountSubscriptions подсчитывает подписки, активные хотя бы в одном месяце периода, так же как CountSubscriptions в PostgreSQL
+func (r *MemorySubscriptionRepository) CountSubscriptions(ctx context.Context, filter CostFilter) ([]SubscriptionCount, error) {
+	from, err := models.ParseMonth(filter.Start)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+	to, err := models.ParseMonth(filter.End)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+	if !filter.GroupBy.Valid() {
+		return nil, fmt.Errorf("%w: unknown cost group %q", ErrValidation, filter.GroupBy)
+	}
+
+	byGroup := make(map[string]int)
+	r.mu.RLock()
+	for _, sub := range r.subs {
+		start, end := activePeriod(sub)
+		if !matchesCostFilter(filter, sub) || start.After(to) || end.Before(from) {
+			continue
+		}
+		byGroup[costGroupKey(filter.GroupBy, sub)]++
+	}
+	r.mu.RUnlock()
+
+	counts := make([]SubscriptionCount, 0, len(byGroup))
+	for group, count := range byGroup {
+		counts = append(counts, SubscriptionCount{Group: group, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Group < counts[j].Group })
+	return counts, nil
+}
+
+// GetServiceStats возвращает по каждому сервису и валюте число подписок, активных в месяце month, и сумму их месячных эквивалентов цен
+func (r *MemorySubscriptionRepository) GetServiceStats(ctx context.Context, month string) ([]ServiceStats, error) {
+	m, err := models.ParseMonth(month)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+
+	type group struct{ service, currency string }
+	r.mu.RLock()
+	byGroup := make(map[group]*ServiceStats)
+	spend := make(map[group]float64)
+	for _, sub := range r.subs {
+		start, end := activePeriod(sub)
+		if m.Before(start) || m.After(end) {
+			continue
+		}
+		g := group{sub.ServiceName, sub.Currency}
+		s, ok := byGroup[g]
+		if !ok {
+			s = &ServiceStats{ServiceName: sub.ServiceName, MonthlySpend: models.Money{Currency: sub.Currency}}
+			byGroup[g] = s
+		}
+		s.ActiveCount++
+		spend[g] += sub.MonthlyEquivalent()
+	}
+	r.mu.RUnlock()
+
+	stats := make([]ServiceStats, 0, len(byGroup))
+	for g, s := range byGroup {
+		s.MonthlySpend.Amount = models.Amount(math.Round(spend[g]))
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].ServiceName != stats[j].ServiceName {
+			return stats[i].ServiceName < stats[j].ServiceName
+		}
+		return stats[i].MonthlySpend.Currency < stats[j].MonthlySpend.Currency
+	})
+	return stats, nil
+}
+
+// Create добавляет новую подписку и возвращает сгенерированный ID
+func (r *MemorySubscriptionRepository) Create(ctx context.Context, sub *models.Subscription) (int, error) {
+	sub.SetDefaults()
+	if err := checkConstraints(*sub); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	sub.ID, sub.Version, sub.CreatedAt, sub.UpdatedAt = r.nextID, 1, now, now
+	r.nextID++
+	r.subs[sub.ID] = clone(*sub)
+	return sub.ID, nil
+}
+
+// GetAll возвращает страницу подписок с учётом фильтров, сортировки и пагинации
+func (r *MemorySubscriptionRepository) GetAll(ctx context.Context, filter SubscriptionFilter) (*SubscriptionPage, error) {
+	if err := filter.Normalize(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+
+	r.mu.RLock()
+	matched := make([]models.Subscription, 0, len(r.subs))
+	for _, sub := range r.subs {
+		if matchesFilter(filter, sub) {
+			matched = append(matched, clone(sub))
+		}
+	}
+	r.mu.RUnlock()
+
+	desc := filter.Order == SortDesc
+	sort.Slice(matched, func(i, j int) bool {
+		cmp := compareSubscriptions(filter.Sort, matched[i], matched[j])
+		if desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+
+	page := &SubscriptionPage{Items: []models.Subscription{}, Total: len(matched)}
+	rest := matched
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+		}
+		last, err := cursorSubscription(c)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+		}
+		// Keyset-пагинация: пропускаем всё до последней записи предыдущей страницы включительно
+		idx := sort.Search(len(rest), func(i int) bool {
+			cmp := compareSubscriptions(filter.Sort, rest[i], last)
+			if desc {
+				return cmp < 0
+			}
+			return cmp > 0
+		})
+		rest = rest[idx:]
+	} else if filter.Offset < len(rest) {
+		rest = rest[filter.Offset:]
+	} else {
+		rest = nil
+	}
+
+	if len(rest) > filter.Limit {
+		page.Items = append(page.Items, rest[:filter.Limit]...)
+		page.NextCursor = encodeCursor(filter, page.Items[filter.Limit-1])
+	} else {
+		page.Items = append(page.Items, rest...)
+	}
+	return page, nil
+}
+
+// GetByID возвращает подписку по ID
+func (r *MemorySubscriptionRepository) GetByID(ctx context.Context, id int) (*models.Subscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sub, ok := r.subs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	sub = clone(sub)
+	return &sub, nil
+}
+
+// Update изменяет данные подписки по ID
+func (r *MemorySubscriptionRepository) Update(ctx context.Context, id int, sub *models.Subscription, version int) error {
+	sub.SetDefaults()
+	if err := checkConstraints(*sub); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.current(id, version)
+	if err != nil {
+		return err
+	}
+	sub.ID, sub.Version, sub.CreatedAt, sub.UpdatedAt = id, current.Version+1, current.CreatedAt, time.Now().UTC()
+	r.subs[id] = clone(*sub)
+	return nil
+}
+
+// current возвращает хранимую подписку, если её версия совпадает с version (или version = 0).
+// Вызывается под блокировкой на запись
+func (r *MemorySubscriptionRepository) current(id, version int) (models.Subscription, error) {
+	sub, ok := r.subs[id]
+	if !ok {
+		return sub, ErrNotFound
+	}
+	if version != 0 && sub.Version != version {
+		return sub, ErrVersionMismatch
+	}
+	return sub, nil
+}
+
+// Patch изменяет только переданные в патче поля подписки
+func (r *MemorySubscriptionRepository) Patch(ctx context.Context, id int, patch models.SubscriptionPatch, version int) error {
+	if patch.IsEmpty() {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, err := r.current(id, version)
+	if err != nil {
+		return err
+	}
+	if patch.ServiceName != nil {
+		sub.ServiceName = *patch.ServiceName
+	}
+	if patch.Price != nil {
+		sub.Price = *patch.Price
+	}
+	if patch.UserID != nil {
+		sub.UserID = *patch.UserID
+	}
+	if patch.StartDate != nil {
+		sub.StartDate = *patch.StartDate
+	}
+	if patch.ClearEndDate {
+		sub.EndDate = nil
+	} else if patch.EndDate != nil {
+		sub.EndDate = patch.EndDate
+	}
+	if patch.BillingPeriod != nil {
+		sub.BillingPeriod = *patch.BillingPeriod
+	}
+	if patch.BillingInterval != nil {
+		sub.BillingInterval = *patch.BillingInterval
+	}
+	if patch.Currency != nil {
+		sub.Currency = *patch.Currency
+	}
+	if err := checkConstraints(sub); err != nil {
+		return err
+	}
+	sub.Version++
+	sub.UpdatedAt = time.Now().UTC()
+	r.subs[id] = clone(sub)
+	return nil
+}
+
+// Delete удаляет подписку по ID
+func (r *MemorySubscriptionRepository) Delete(ctx context.Context, id int, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.current(id, version); err != nil {
+		return err
+	}
+	delete(r.subs, id)
+	return nil
+}
+
+// clone копирует подписку вместе с end_date, чтобы вызывающий код не мог изменить хранимые данные
+func clone(sub models.Subscription) models.Subscription {
+	if sub.EndDate != nil {
+		end := *sub.EndDate
+		sub.EndDate = &end
+	}
+	return sub
+}
+
+// checkConstraints повторяет ограничения таблицы subscriptions
+func checkConstraints(sub models.Subscription) error {
+	start, err := models.ParseMonth(sub.StartDate)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+	if sub.EndDate != nil {
+		end, err := models.ParseMonth(*sub.EndDate)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrValidation, err)
+		}
+		if end.Before(start) {
+			return fmt.Errorf("%w: end_date is before start_date", ErrValidation)
+		}
+	}
+	switch sub.BillingPeriod {
+	case models.BillingWeekly, models.BillingMonthly, models.BillingQuarterly, models.BillingYearly:
+	default:
+		return fmt.Errorf("%w: unknown billing_period %q", ErrValidation, sub.BillingPeriod)
+	}
+	if sub.BillingInterval <= 0 {
+		return fmt.Errorf("%w: billing_interval must be positive", ErrValidation)
+	}
+	if len(sub.Currency) != 3 {
+		return fmt.Errorf("%w: currency must be a 3-letter code", ErrValidation)
+	}
+	return nil
+}
+
+// activePeriod возвращает первый и последний месяц активности подписки.
+// Для бессрочной подписки последний месяц — максимально возможный
+func activePeriod(sub models.Subscription) (time.Time, time.Time) {
+	start, _ := models.ParseMonth(sub.StartDate)
+	end := time.Date(9999, time.December, 1, 0, 0, 0, 0, time.UTC)
+	if sub.EndDate != nil {
+		end, _ = models.ParseMonth(*sub.EndDate)
+	}
+	return start, end
+}
+
+func laterOf(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earlierOf(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// matchesCostFilter проверяет подписку по фильтрам подсчёта стоимости
+func matchesCostFilter(f CostFilter, sub models.Subscription) bool {
+	if len(f.UserIDs) > 0 && !slices.Contains(f.UserIDs, sub.UserID) {
+		return false
+	}
+	return len(f.ServiceNames) == 0 || slices.Contains(f.ServiceNames, sub.ServiceName)
+}
+
+// costGroupKey возвращает ключ группы подписки при разбивке g
+func costGroupKey(g CostGroup, sub models.Subscription) string {
+	switch g {
+	case CostGroupService:
+		return sub.ServiceName
+	case CostGroupUser:
+		return sub.UserID.String()
+	}
+	return ""
+}
+
+// matchesFilter проверяет подписку по фильтрам списка (без учёта курсора)
+func matchesFilter(f SubscriptionFilter, sub models.Subscription) bool {
+	if f.UserID != uuid.Nil && sub.UserID != f.UserID {
+		return false
+	}
+	if f.ServiceName != "" && sub.ServiceName != f.ServiceName {
+		return false
+	}
+	if f.ServiceNamePrefix != "" && !strings.HasPrefix(sub.ServiceName, f.ServiceNamePrefix) {
+		return false
+	}
+	if f.MinPrice != nil && sub.Price < *f.MinPrice {
+		return false
+	}
+	if f.MaxPrice != nil && sub.Price > *f.MaxPrice {
+		return false
+	}
+	if f.ActiveIn != "" {
+		month, _ := models.ParseMonth(f.ActiveIn)
+		start, end := activePeriod(sub)
+		if month.Before(start) || month.After(end) {
+			return false
+		}
+	}
+	if sub.CreatedAt.Before(f.CreatedSince) || sub.UpdatedAt.Before(f.UpdatedSince) {
+		return false
+	}
+	return true
+}
+
+// compareSubscriptions сравнивает подписки по колонке сортировки, а при равенстве — по ID
+func compareSubscriptions(col string, a, b models.Subscription) int {
+	var cmp int
+	switch col {
+	case "service_name":
+		cmp = strings.Compare(a.ServiceName, b.ServiceName)
+	case "price":
+		cmp = compareInts(a.Price, b.Price)
+	case "user_id":
+		cmp = bytes.Compare(a.UserID[:], b.UserID[:])
+	case "start_date":
+		aStart, _ := activePeriod(a)
+		bStart, _ := activePeriod(b)
+		cmp = aStart.Compare(bStart)
+	case "end_date":
+		_, aEnd := activePeriod(a)
+		_, bEnd := activePeriod(b)
+		cmp = aEnd.Compare(bEnd)
+	}
+	if cmp != 0 {
+		return cmp
+	}
+	return compareInts(a.ID, b.ID)
+}
+
+func compareInts[T ~int | ~int64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// cursorSubscription восстанавливает из курсора подписку с тем же ключом сортировки,
+// что у последней записи предыдущей страницы
+func cursorSubscription(c cursor) (models.Subscription, error) {
+	sub := models.Subscription{ID: c.ID}
+	var err error
+	switch c.Sort {
+	case "service_name":
+		sub.ServiceName = c.Value
+	case "price":
+		var price int64
+		price, err = strconv.ParseInt(c.Value, 10, 64)
+		sub.Price = models.Amount(price)
+	case "user_id":
+		sub.UserID, err = uuid.Parse(c.Value)
+	case "start_date":
+		sub.StartDate = c.Value
+	case "end_date":
+		if c.Value != "" {
+			sub.EndDate = &c.Value
+		}
+	}
+	if err != nil {
+		return sub, ErrInvalidCursor
+	}
+	return sub, nil
+}
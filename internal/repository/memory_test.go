@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"rest-service/internal/models"
+)
+
+func TestMemorySubscriptionRepository(t *testing.T) {
+	testRepositorySuite(t, func(t *testing.T) SubscriptionRepository {
+		return NewMemorySubscriptionRepository()
+	})
+}
+
+func TestMemoryAPIKeyRepository(t *testing.T) {
+	testAPIKeyRepositorySuite(t, NewMemoryAPIKeyRepository())
+}
+
+func TestMemoryIdempotencyRepository(t *testing.T) {
+	testIdempotencyRepositorySuite(t, NewMemoryIdempotencyRepository())
+}
+
+func TestMemoryExchangeRateRepository(t *testing.T) {
+	testExchangeRateRepositorySuite(t, NewMemoryExchangeRateRepository())
+}
+
+func TestMemorySubscriptionRepository_Concurrent(t *testing.T) {
+	repo := NewMemorySubscriptionRepository()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, err := repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: 1, UserID: uuid.New(), StartDate: "01-2025"})
+			assert.NoError(t, err)
+			_, err = repo.GetByID(ctx, id)
+			assert.NoError(t, err)
+			_, err = repo.GetAll(ctx, SubscriptionFilter{})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	page, err := repo.GetAll(ctx, SubscriptionFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, 50, page.Total)
+}
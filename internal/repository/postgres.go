@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"rest-service/internal/models"
+	"strings"
 
 	"github.com/google/uuid"
 )
@@ -18,112 +20,487 @@ func NewPostgresSubscriptionRepository(db *sql.DB) SubscriptionRepository {
 	return &PostgresSubscriptionRepository{db: db}
 }
 
-// GetSum подсчитывает сумму стоимости подписок за период с фильтрами
-func (r *PostgresSubscriptionRepository) GetSum(ctx context.Context, start, end string, userID uuid.UUID, serviceName string) (int, error) {
-	query := `SELECT COALESCE(SUM(price), 0) FROM subscriptions WHERE start_date <= $1 AND (end_date IS NULL OR end_date >= $2)`
-	args := []interface{}{end, start}
+// subscriptionColumns — список колонок для выборки подписки.
+// Даты хранятся как DATE, а наружу отдаются в формате MM-YYYY
+const subscriptionColumns = `id, service_name, price, currency, user_id, TO_CHAR(start_date, 'MM-YYYY'), TO_CHAR(end_date, 'MM-YYYY'),
+    billing_period, billing_interval, version, created_at, updated_at`
 
-	// Управляем параметрами запроса динамически
-	if userID != uuid.Nil {
-		query += fmt.Sprintf(" AND user_id = $%d", len(args)+1)
-		args = append(args, userID.String())
+// billingStepSQL — промежуток между списаниями: в днях для weekly и в месяцах для остальных периодов.
+// Выражения для подсчёта стоимости повторяют методы models.Subscription и подходят и для SQLite
+const billingStepSQL = `billing_interval * CASE billing_period WHEN 'weekly' THEN 7 WHEN 'quarterly' THEN 3 WHEN 'yearly' THEN 12 ELSE 1 END`
+
+// monthlyEquivalentSQL — цена подписки в пересчёте на один месяц
+const monthlyEquivalentSQL = `price * CASE billing_period WHEN 'weekly' THEN 365.25 / 12 ELSE 1.0 END / (` + billingStepSQL + `)`
+
+// scanPostgresSubscription читает подписку, выбранную по subscriptionColumns
+func scanPostgresSubscription(row rowScanner) (*models.Subscription, error) {
+	var sub models.Subscription
+	var userID string
+	err := row.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.Currency, &userID, &sub.StartDate, &sub.EndDate,
+		&sub.BillingPeriod, &sub.BillingInterval, &sub.Version, &sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		return nil, mapPostgresError(err)
 	}
-	if serviceName != "" {
-		query += fmt.Sprintf(" AND service_name = $%d", len(args)+1)
-		args = append(args, serviceName)
+	sub.UserID, err = uuid.Parse(userID)
+	if err != nil {
+		return nil, err
 	}
+	sub.CreatedAt, sub.UpdatedAt = sub.CreatedAt.UTC(), sub.UpdatedAt.UTC()
+	return &sub, nil
+}
 
-	var sum int
-	err := r.db.QueryRowContext(ctx, query, args...).Scan(&sum)
-	return sum, err
+// GetCosts подсчитывает стоимость подписок по месяцам периода, группам filter.GroupBy и валютам.
+// Каждая подписка учитывается в месяцах периода, в которые она активна (с учётом её start_date и end_date)
+func (r *PostgresSubscriptionRepository) GetCosts(ctx context.Context, filter CostFilter) (costs []MonthlyCost, err error) {
+	var args queryArgs
+	from, to := args.add(filter.Start), args.add(filter.End)
+	conds := costConditions(filter, &args)
+	group, err := costGroupSQL(filter.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Смещения начала и конца месяца от начала подписки считаются в единицах шага оплаты (дни или месяцы),
+	// и число списаний до смещения x равно ceil(x / step)
+	query := `SELECT TO_CHAR(month, 'MM-YYYY'), grp, currency,
+                     SUM(price * ((end_offset + step - 1) / step - (start_offset + step - 1) / step))::BIGINT,
+                     ROUND(SUM(monthly))::BIGINT, COUNT(*)
+              FROM (
+                  SELECT month, ` + group + ` AS grp, currency, price,
+                         ` + billingStepSQL + ` AS step,
+                         ` + monthlyEquivalentSQL + ` AS monthly,
+                         CASE WHEN billing_period = 'weekly' THEN month - start_date
+                              ELSE ` + pgMonthsDiff("start_date", "month") + ` END AS start_offset,
+                         CASE WHEN billing_period = 'weekly' THEN (month + INTERVAL '1 month')::DATE - start_date
+                              ELSE ` + pgMonthsDiff("start_date", "month") + ` + 1 END AS end_offset
+                  FROM (
+                      SELECT generate_series(TO_DATE(` + from + `, 'MM-YYYY')::TIMESTAMP, TO_DATE(` + to + `, 'MM-YYYY')::TIMESTAMP, INTERVAL '1 month')::DATE AS month
+                  ) AS months
+                  JOIN subscriptions ON start_date <= month AND (end_date IS NULL OR end_date >= month)` + whereClause(conds) + `
+              ) AS charges
+              GROUP BY month, grp, currency
+              ORDER BY month, grp, currency`
+
+	ctx, span := startQuerySpan(ctx, "PostgresSubscriptionRepository.GetCosts", "SELECT", query)
+	defer func() { span.end(err) }()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, mapPostgresError(err)
+	}
+	defer rows.Close()
+	costs, err = scanMonthlyCosts(rows, mapPostgresError)
+	span.returnedRows(len(costs))
+	return costs, err
 }
 
-// Create добавляет новую подписку и возвращает сгенерированный ID
-func (r *PostgresSubscriptionRepository) Create(ctx context.Context, sub *models.Subscription) (int, error) {
-	query := `INSERT INTO subscriptions (service_name, price, user_id, start_date, end_date) 
-              VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	err := r.db.QueryRowContext(ctx, query, sub.ServiceName, sub.Price, sub.UserID.String(), sub.StartDate, sub.EndDate).Scan(&sub.ID)
-	return sub.ID, err
+// costConditions строит условия WHERE по фильтрам подсчёта стоимости
+func costConditions(f CostFilter, args *queryArgs) []string {
+	var conds []string
+	if len(f.UserIDs) > 0 {
+		ids := make([]string, len(f.UserIDs))
+		for i, id := range f.UserIDs {
+			ids[i] = args.add(id.String())
+		}
+		conds = append(conds, "user_id IN ("+strings.Join(ids, ", ")+")")
+	}
+	if len(f.ServiceNames) > 0 {
+		names := make([]string, len(f.ServiceNames))
+		for i, name := range f.ServiceNames {
+			names[i] = args.add(name)
+		}
+		conds = append(conds, "service_name IN ("+strings.Join(names, ", ")+")")
+	}
+	return conds
+}
+
+// costGroupSQL возвращает SQL-выражение ключа разбивки стоимости внутри месяца
+func costGroupSQL(g CostGroup) (string, error) {
+	switch g {
+	case CostGroupNone:
+		return "''", nil
+	case CostGroupService:
+		return "service_name", nil
+	case CostGroupUser:
+		return "CAST(user_id AS TEXT)", nil
+	}
+	return "", fmt.Errorf("%w: unknown cost group %q", ErrValidation, g)
+}
+
+// scanMonthlyCosts читает строки (месяц MM-YYYY, группа, currency, charged, normalized, active) в срез MonthlyCost
+func scanMonthlyCosts(rows *sql.Rows, mapError func(error) error) ([]MonthlyCost, error) {
+	costs := []MonthlyCost{}
+	for rows.Next() {
+		var c MonthlyCost
+		var month string
+		if err := rows.Scan(&month, &c.Group, &c.Charged.Currency, &c.Charged.Amount, &c.Normalized.Amount, &c.Active); err != nil {
+			return nil, mapError(err)
+		}
+		c.Normalized.Currency = c.Charged.Currency
+		var err error
+		if c.Month, err = models.ParseMonth(month); err != nil {
+			return nil, err
+		}
+		costs = append(costs, c)
+	}
+	return costs, mapError(rows.Err())
+}
+
+// pgMonthsDiff возвращает SQL-выражение для числа месяцев от даты from до даты to
+func pgMonthsDiff(from, to string) string {
+	return fmt.Sprintf("((DATE_PART('year', %[2]s) - DATE_PART('year', %[1]s)) * 12 + DATE_PART('month', %[2]s) - DATE_PART('month', %[1]s))::INTEGER", from, to)
 }
 
-// GetAll возвращает все подписки
-func (r *PostgresSubscriptionRepository) GetAll(ctx context.Context) ([]models.Subscription, error) {
-	query := `SELECT id, service_name, price, user_id, start_date, end_date FROM subscriptions`
-	rows, err := r.db.QueryContext(ctx, query)
+// CountSubscriptions подсчитывает подписки, активные хотя бы в одном месяце периода, по группам filter.GroupBy
+func (r *PostgresSubscriptionRepository) CountSubscriptions(ctx context.Context, filter CostFilter) (counts []SubscriptionCount, err error) {
+	var args queryArgs
+	from, to := args.add(filter.Start), args.add(filter.End)
+	conds := append([]string{
+		"start_date <= TO_DATE(" + to + ", 'MM-YYYY')",
+		"(end_date IS NULL OR end_date >= TO_DATE(" + from + ", 'MM-YYYY'))",
+	}, costConditions(filter, &args)...)
+	group, err := costGroupSQL(filter.GroupBy)
 	if err != nil {
 		return nil, err
 	}
+	query := `SELECT ` + group + ` AS grp, COUNT(*) FROM subscriptions` + whereClause(conds) + ` GROUP BY grp ORDER BY grp`
+
+	ctx, span := startQuerySpan(ctx, "PostgresSubscriptionRepository.CountSubscriptions", "SELECT", query)
+	defer func() { span.end(err) }()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, mapPostgresError(err)
+	}
 	defer rows.Close()
+	counts, err = scanSubscriptionCounts(rows, mapPostgresError)
+	span.returnedRows(len(counts))
+	return counts, err
+}
 
-	var subs []models.Subscription
+// scanSubscriptionCounts читает строки (группа, count) в срез SubscriptionCount
+func scanSubscriptionCounts(rows *sql.Rows, mapError func(error) error) ([]SubscriptionCount, error) {
+	counts := []SubscriptionCount{}
 	for rows.Next() {
-		var sub models.Subscription
-		var userID string
-		err = rows.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &userID, &sub.StartDate, &sub.EndDate)
-		if err != nil {
-			return nil, err
+		var c SubscriptionCount
+		if err := rows.Scan(&c.Group, &c.Count); err != nil {
+			return nil, mapError(err)
 		}
-		sub.UserID, err = uuid.Parse(userID)
-		if err != nil {
-			return nil, err
+		counts = append(counts, c)
+	}
+	return counts, mapError(rows.Err())
+}
+
+// GetServiceStats возвращает по каждому сервису и валюте число подписок, активных в месяце month, и сумму их месячных эквивалентов цен
+func (r *PostgresSubscriptionRepository) GetServiceStats(ctx context.Context, month string) (stats []ServiceStats, err error) {
+	query := `SELECT service_name, currency, COUNT(*), ROUND(SUM(` + monthlyEquivalentSQL + `))::BIGINT
+              FROM subscriptions
+              WHERE start_date <= TO_DATE($1, 'MM-YYYY') AND (end_date IS NULL OR end_date >= TO_DATE($1, 'MM-YYYY'))
+              GROUP BY service_name, currency
+              ORDER BY service_name, currency`
+	ctx, span := startQuerySpan(ctx, "PostgresSubscriptionRepository.GetServiceStats", "SELECT", query)
+	defer func() { span.end(err) }()
+
+	rows, err := r.db.QueryContext(ctx, query, month)
+	if err != nil {
+		return nil, mapPostgresError(err)
+	}
+	defer rows.Close()
+	stats, err = scanServiceStats(rows, mapPostgresError)
+	span.returnedRows(len(stats))
+	return stats, err
+}
+
+// scanServiceStats читает строки (service_name, currency, count, sum) в срез ServiceStats
+func scanServiceStats(rows *sql.Rows, mapError func(error) error) ([]ServiceStats, error) {
+	stats := []ServiceStats{}
+	for rows.Next() {
+		var s ServiceStats
+		if err := rows.Scan(&s.ServiceName, &s.MonthlySpend.Currency, &s.ActiveCount, &s.MonthlySpend.Amount); err != nil {
+			return nil, mapError(err)
 		}
-		subs = append(subs, sub)
+		stats = append(stats, s)
 	}
-	return subs, nil
+	return stats, mapError(rows.Err())
 }
 
-// GetByID возвращает подписку по ID
-func (r *PostgresSubscriptionRepository) GetByID(ctx context.Context, id int) (*models.Subscription, error) {
-	query := `SELECT id, service_name, price, user_id, start_date, end_date FROM subscriptions WHERE id = $1`
-	row := r.db.QueryRowContext(ctx, query, id)
+// Create добавляет новую подписку и возвращает сгенерированный ID
+func (r *PostgresSubscriptionRepository) Create(ctx context.Context, sub *models.Subscription) (_ int, err error) {
+	sub.SetDefaults()
+	query := `INSERT INTO subscriptions (service_name, price, user_id, start_date, end_date, billing_period, billing_interval, currency)
+              VALUES ($1, $2, $3, TO_DATE($4, 'MM-YYYY'), TO_DATE($5, 'MM-YYYY'), $6, $7, $8) RETURNING id, version, created_at, updated_at`
+	ctx, span := startQuerySpan(ctx, "PostgresSubscriptionRepository.Create", "INSERT", query)
+	defer func() { span.end(err) }()
 
-	var sub models.Subscription
-	var userID string
-	err := row.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &userID, &sub.StartDate, &sub.EndDate)
+	err = r.db.QueryRowContext(ctx, query, sub.ServiceName, sub.Price, sub.UserID.String(), sub.StartDate, sub.EndDate,
+		sub.BillingPeriod, sub.BillingInterval, sub.Currency).
+		Scan(&sub.ID, &sub.Version, &sub.CreatedAt, &sub.UpdatedAt)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
+		return 0, mapPostgresError(err)
+	}
+	sub.CreatedAt, sub.UpdatedAt = sub.CreatedAt.UTC(), sub.UpdatedAt.UTC()
+	span.affectedRows(1)
+	return sub.ID, nil
+}
+
+// sortExpressions — SQL-выражения для колонок сортировки и для значения курсора той же колонки.
+// Бессрочные подписки (end_date IS NULL) при сортировке по end_date считаются самыми поздними
+var sortExpressions = map[string]struct{ column, value string }{
+	"id":           {"id", "%s::INTEGER"},
+	"service_name": {"service_name", "%s"},
+	"price":        {"price", "%s::BIGINT"},
+	"user_id":      {"user_id", "%s::UUID"},
+	"start_date":   {"start_date", "TO_DATE(%s, 'MM-YYYY')"},
+	"end_date":     {"COALESCE(end_date, 'infinity'::DATE)", "COALESCE(TO_DATE(NULLIF(%s, ''), 'MM-YYYY'), 'infinity'::DATE)"},
+}
+
+// queryArgs накапливает аргументы запроса и выдаёт для них плейсхолдеры $N
+type queryArgs []interface{}
+
+func (a *queryArgs) add(v interface{}) string {
+	*a = append(*a, v)
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+// listConditions строит условия WHERE по фильтрам списка (без учёта курсора)
+func listConditions(f SubscriptionFilter, args *queryArgs) []string {
+	var conds []string
+	if f.UserID != uuid.Nil {
+		conds = append(conds, "user_id = "+args.add(f.UserID.String()))
+	}
+	if f.ServiceName != "" {
+		conds = append(conds, "service_name = "+args.add(f.ServiceName))
+	}
+	if f.ServiceNamePrefix != "" {
+		conds = append(conds, "service_name LIKE "+args.add(likePrefix(f.ServiceNamePrefix)))
+	}
+	if f.MinPrice != nil {
+		conds = append(conds, "price >= "+args.add(*f.MinPrice))
+	}
+	if f.MaxPrice != nil {
+		conds = append(conds, "price <= "+args.add(*f.MaxPrice))
+	}
+	if f.ActiveIn != "" {
+		month := "TO_DATE(" + args.add(f.ActiveIn) + ", 'MM-YYYY')"
+		conds = append(conds, "start_date <= "+month+" AND (end_date IS NULL OR end_date >= "+month+")")
+	}
+	if !f.CreatedSince.IsZero() {
+		conds = append(conds, "created_at >= "+args.add(f.CreatedSince))
+	}
+	if !f.UpdatedSince.IsZero() {
+		conds = append(conds, "updated_at >= "+args.add(f.UpdatedSince))
+	}
+	return conds
+}
+
+// likePrefix экранирует спецсимволы LIKE и превращает строку в шаблон поиска по префиксу
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+}
+
+// versionCondition возвращает проверку версии для условного изменения или пустую строку, если version = 0
+func versionCondition(version int, args *queryArgs) string {
+	if version == 0 {
+		return ""
+	}
+	return " AND version = " + args.add(version)
+}
+
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// GetAll возвращает страницу подписок с учётом фильтров, сортировки и пагинации
+func (r *PostgresSubscriptionRepository) GetAll(ctx context.Context, filter SubscriptionFilter) (page *SubscriptionPage, err error) {
+	if err := filter.Normalize(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+
+	var countArgs queryArgs
+	countQuery := `SELECT COUNT(*) FROM subscriptions` + whereClause(listConditions(filter, &countArgs))
+
+	var args queryArgs
+	conds := listConditions(filter, &args)
+	expr := sortExpressions[filter.Sort]
+	direction, cmp := "ASC", ">"
+	if filter.Order == SortDesc {
+		direction, cmp = "DESC", "<"
+	}
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrValidation, err)
 		}
+		// Keyset-пагинация: продолжаем строго после последней записи предыдущей страницы
+		conds = append(conds, fmt.Sprintf("(%s, id) %s (%s, %s::INTEGER)",
+			expr.column, cmp, fmt.Sprintf(expr.value, args.add(c.Value)), args.add(c.ID)))
+	}
+
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions` + whereClause(conds) +
+		fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %s", expr.column, direction, direction, args.add(filter.Limit+1))
+	if filter.Cursor == "" && filter.Offset > 0 {
+		query += " OFFSET " + args.add(filter.Offset)
+	}
+
+	ctx, span := startQuerySpan(ctx, "PostgresSubscriptionRepository.GetAll", "SELECT", query)
+	defer func() { span.end(err) }()
+
+	page = &SubscriptionPage{Items: []models.Subscription{}}
+	if page.Total, err = r.count(ctx, countQuery, countArgs); err != nil {
 		return nil, err
 	}
-	sub.UserID, err = uuid.Parse(userID)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, mapPostgresError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		sub, err := scanPostgresSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, *sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, mapPostgresError(err)
+	}
+	span.returnedRows(len(page.Items))
+
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	if len(page.Items) > filter.Limit {
+		page.Items = page.Items[:filter.Limit]
+		page.NextCursor = encodeCursor(filter, page.Items[filter.Limit-1])
+	}
+	return page, nil
+}
+
+// count выполняет запрос COUNT(*) в отдельном спане
+func (r *PostgresSubscriptionRepository) count(ctx context.Context, query string, args queryArgs) (total int, err error) {
+	ctx, span := startQuerySpan(ctx, "PostgresSubscriptionRepository.count", "SELECT", query)
+	defer func() { span.end(err) }()
+
+	err = mapPostgresError(r.db.QueryRowContext(ctx, query, args...).Scan(&total))
+	return total, err
+}
+
+// GetByID возвращает подписку по ID
+func (r *PostgresSubscriptionRepository) GetByID(ctx context.Context, id int) (_ *models.Subscription, err error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE id = $1`
+	ctx, span := startQuerySpan(ctx, "PostgresSubscriptionRepository.GetByID", "SELECT", query)
+	defer func() { span.end(err) }()
+
+	sub, err := scanPostgresSubscription(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		return nil, err
 	}
-	return &sub, nil
+	span.returnedRows(1)
+	return sub, nil
 }
 
 // Update изменяет данные подписки по ID
-func (r *PostgresSubscriptionRepository) Update(ctx context.Context, id int, sub *models.Subscription) error {
-	query := `UPDATE subscriptions SET service_name=$1, price=$2, user_id=$3, start_date=$4, end_date=$5 WHERE id=$6`
-	result, err := r.db.ExecContext(ctx, query, sub.ServiceName, sub.Price, sub.UserID.String(), sub.StartDate, sub.EndDate, id)
-	if err != nil {
-		return err
+func (r *PostgresSubscriptionRepository) Update(ctx context.Context, id int, sub *models.Subscription, version int) (err error) {
+	sub.SetDefaults()
+	args := queryArgs{sub.ServiceName, sub.Price, sub.UserID.String(), sub.StartDate, sub.EndDate, sub.BillingPeriod, sub.BillingInterval, sub.Currency, id}
+	query := `UPDATE subscriptions SET service_name=$1, price=$2, user_id=$3, start_date=TO_DATE($4, 'MM-YYYY'), end_date=TO_DATE($5, 'MM-YYYY'),
+              billing_period=$6, billing_interval=$7, currency=$8, version = version + 1, updated_at = NOW() WHERE id=$9` + versionCondition(version, &args) + ` RETURNING version, created_at, updated_at`
+	ctx, span := startQuerySpan(ctx, "PostgresSubscriptionRepository.Update", "UPDATE", query)
+	defer func() { span.end(err) }()
+
+	var stored models.Subscription
+	if err = r.db.QueryRowContext(ctx, query, args...).Scan(&stored.Version, &stored.CreatedAt, &stored.UpdatedAt); err != nil {
+		return r.conditionalError(ctx, id, version, mapPostgresError(err))
 	}
+	span.affectedRows(1)
+	sub.ID, sub.Version, sub.CreatedAt, sub.UpdatedAt = id, stored.Version, stored.CreatedAt.UTC(), stored.UpdatedAt.UTC()
+	return nil
+}
 
-	affected, err := result.RowsAffected()
-	if err != nil {
-		return err
+// Patch изменяет только переданные в патче поля подписки
+func (r *PostgresSubscriptionRepository) Patch(ctx context.Context, id int, patch models.SubscriptionPatch, version int) error {
+	if patch.IsEmpty() {
+		return nil
 	}
-	if affected == 0 {
-		return sql.ErrNoRows
+
+	var args queryArgs
+	var set []string
+	if patch.ServiceName != nil {
+		set = append(set, "service_name = "+args.add(*patch.ServiceName))
 	}
-	return nil
+	if patch.Price != nil {
+		set = append(set, "price = "+args.add(*patch.Price))
+	}
+	if patch.UserID != nil {
+		set = append(set, "user_id = "+args.add(patch.UserID.String()))
+	}
+	if patch.StartDate != nil {
+		set = append(set, "start_date = TO_DATE("+args.add(*patch.StartDate)+", 'MM-YYYY')")
+	}
+	if patch.ClearEndDate {
+		set = append(set, "end_date = NULL")
+	} else if patch.EndDate != nil {
+		set = append(set, "end_date = TO_DATE("+args.add(*patch.EndDate)+", 'MM-YYYY')")
+	}
+	if patch.BillingPeriod != nil {
+		set = append(set, "billing_period = "+args.add(*patch.BillingPeriod))
+	}
+	if patch.BillingInterval != nil {
+		set = append(set, "billing_interval = "+args.add(*patch.BillingInterval))
+	}
+	if patch.Currency != nil {
+		set = append(set, "currency = "+args.add(*patch.Currency))
+	}
+
+	set = append(set, "version = version + 1", "updated_at = NOW()")
+
+	query := `UPDATE subscriptions SET ` + strings.Join(set, ", ") + ` WHERE id = ` + args.add(id) + versionCondition(version, &args)
+	err := r.execOne(ctx, "PostgresSubscriptionRepository.Patch", "UPDATE", query, args...)
+	return r.conditionalError(ctx, id, version, err)
 }
 
 // Delete удаляет подписку по ID
-func (r *PostgresSubscriptionRepository) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM subscriptions WHERE id = $1`
-	result, err := r.db.ExecContext(ctx, query, id)
-	if err != nil {
+func (r *PostgresSubscriptionRepository) Delete(ctx context.Context, id int, version int) error {
+	args := queryArgs{id}
+	query := `DELETE FROM subscriptions WHERE id = $1` + versionCondition(version, &args)
+	err := r.execOne(ctx, "PostgresSubscriptionRepository.Delete", "DELETE", query, args...)
+	return r.conditionalError(ctx, id, version, err)
+}
+
+// conditionalError уточняет ErrNotFound условного изменения: если подписка существует, значит не совпала версия
+func (r *PostgresSubscriptionRepository) conditionalError(ctx context.Context, id, version int, err error) error {
+	if version == 0 || !errors.Is(err, ErrNotFound) {
 		return err
 	}
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM subscriptions WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return mapPostgresError(err)
+	}
+	if exists {
+		return ErrVersionMismatch
+	}
+	return ErrNotFound
+}
+
+// execOne выполняет UPDATE или DELETE одной подписки и возвращает ErrNotFound, если запрос не затронул ни одной строки
+func (r *PostgresSubscriptionRepository) execOne(ctx context.Context, spanName, operation, query string, args ...interface{}) (err error) {
+	ctx, span := startQuerySpan(ctx, spanName, operation, query)
+	defer func() { span.end(err) }()
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return mapPostgresError(err)
+	}
 	affected, err := result.RowsAffected()
 	if err != nil {
-		return err
+		return mapPostgresError(err)
 	}
+	span.affectedRows(affected)
 	if affected == 0 {
-		return sql.ErrNoRows
+		return ErrNotFound
 	}
 	return nil
 }
@@ -2,17 +2,31 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"os"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 
 	"rest-service/internal/models"
+	"rest-service/migrations"
 )
 
-func TestPostgresSubscriptionRepository_GetSum(t *testing.T) {
+// testTime — время создания и изменения подписок в ответах sqlmock
+var testTime = time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func TestPostgresSubscriptionRepository_GetCosts(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer db.Close()
@@ -20,17 +34,63 @@ func TestPostgresSubscriptionRepository_GetSum(t *testing.T) {
 	repo := &PostgresSubscriptionRepository{db: db}
 	ctx := context.Background()
 	userID := uuid.New()
-	start, end := "01-2023", "12-2023"
-	serviceName := "Netflix"
+	filter := CostFilter{Start: "01-2023", End: "12-2023", UserIDs: []uuid.UUID{userID}, ServiceNames: []string{"Netflix", "Spotify"}}
 
-	rows := sqlmock.NewRows([]string{"coalesce"}).AddRow(100)
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(SUM(price), 0) FROM subscriptions WHERE")).
-		WithArgs(end, start, userID.String(), serviceName).
+	rows := sqlmock.NewRows([]string{"month", "grp", "currency", "charged", "normalized", "active"}).
+		AddRow("01-2023", "", "RUB", 100, 90, 2).
+		AddRow("01-2023", "", "USD", 5, 5, 1)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT TO_CHAR(month, 'MM-YYYY'), grp, currency,")).
+		WithArgs(filter.Start, filter.End, userID.String(), "Netflix", "Spotify").
 		WillReturnRows(rows)
 
-	sum, err := repo.GetSum(ctx, start, end, userID, serviceName)
+	costs, err := repo.GetCosts(ctx, filter)
 	assert.NoError(t, err)
-	assert.Equal(t, 100, sum)
+	january := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []MonthlyCost{
+		{Month: january, Charged: models.Money{Amount: 100, Currency: "RUB"}, Normalized: models.Money{Amount: 90, Currency: "RUB"}, Active: 2},
+		{Month: january, Charged: models.Money{Amount: 5, Currency: "USD"}, Normalized: models.Money{Amount: 5, Currency: "USD"}, Active: 1},
+	}, costs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresSubscriptionRepository_CountSubscriptions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &PostgresSubscriptionRepository{db: db}
+	filter := CostFilter{Start: "01-2025", End: "12-2025", ServiceNames: []string{"Netflix"}, GroupBy: CostGroupUser}
+	userID := uuid.New()
+	rows := sqlmock.NewRows([]string{"grp", "count"}).AddRow(userID.String(), 2)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT CAST(user_id AS TEXT) AS grp, COUNT(*) FROM subscriptions WHERE start_date <= TO_DATE($2, 'MM-YYYY') AND (end_date IS NULL OR end_date >= TO_DATE($1, 'MM-YYYY')) AND service_name IN ($3) GROUP BY grp ORDER BY grp")).
+		WithArgs(filter.Start, filter.End, "Netflix").
+		WillReturnRows(rows)
+
+	counts, err := repo.CountSubscriptions(context.Background(), filter)
+	assert.NoError(t, err)
+	assert.Equal(t, []SubscriptionCount{{Group: userID.String(), Count: 2}}, counts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresSubscriptionRepository_GetServiceStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &PostgresSubscriptionRepository{db: db}
+	rows := sqlmock.NewRows([]string{"service_name", "currency", "count", "sum"}).
+		AddRow("Netflix", "RUB", 2, 1200).
+		AddRow("Spotify", "RUB", 1, 200)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT service_name, currency, COUNT(*), ROUND(SUM(price * CASE billing_period")).
+		WithArgs("02-2025").
+		WillReturnRows(rows)
+
+	stats, err := repo.GetServiceStats(context.Background(), "02-2025")
+	assert.NoError(t, err)
+	assert.Equal(t, []ServiceStats{
+		{ServiceName: "Netflix", ActiveCount: 2, MonthlySpend: models.Money{Amount: 1200, Currency: "RUB"}},
+		{ServiceName: "Spotify", ActiveCount: 1, MonthlySpend: models.Money{Amount: 200, Currency: "RUB"}},
+	}, stats)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -49,14 +109,15 @@ func TestPostgresSubscriptionRepository_Create(t *testing.T) {
 		EndDate:     nil,
 	}
 
-	rows := sqlmock.NewRows([]string{"id"}).AddRow(1)
+	rows := sqlmock.NewRows([]string{"id", "version", "created_at", "updated_at"}).AddRow(1, 1, testTime, testTime)
 	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO subscriptions`)).
-		WithArgs(sub.ServiceName, sub.Price, sub.UserID.String(), sub.StartDate, sub.EndDate).
+		WithArgs(sub.ServiceName, sub.Price, sub.UserID.String(), sub.StartDate, sub.EndDate, "monthly", 1, "RUB").
 		WillReturnRows(rows)
 
 	id, err := repo.Create(ctx, sub)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, id)
+	assert.Equal(t, testTime, sub.CreatedAt)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -69,16 +130,65 @@ func TestPostgresSubscriptionRepository_GetAll(t *testing.T) {
 	ctx := context.Background()
 
 	userID := uuid.New()
-	rows := sqlmock.NewRows([]string{"id", "service_name", "price", "user_id", "start_date", "end_date"}).
-		AddRow(1, "Netflix", 500, userID.String(), "10-2025", nil)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM subscriptions")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	rows := sqlmock.NewRows([]string{"id", "service_name", "price", "currency", "user_id", "start_date", "end_date", "billing_period", "billing_interval", "version", "created_at", "updated_at"}).
+		AddRow(1, "Netflix", 500, "RUB", userID.String(), "10-2025", nil, "monthly", 1, 1, testTime, testTime)
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, service_name, price, user_id, start_date, end_date FROM subscriptions")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT " + subscriptionColumns + " FROM subscriptions ORDER BY id ASC, id ASC LIMIT $1")).
+		WithArgs(DefaultLimit + 1).
 		WillReturnRows(rows)
 
-	subs, err := repo.GetAll(ctx)
+	page, err := repo.GetAll(ctx, SubscriptionFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Equal(t, 1, page.Total)
+	assert.Empty(t, page.NextCursor)
+	assert.Equal(t, "Netflix", page.Items[0].ServiceName)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresSubscriptionRepository_GetAll_FilterAndCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &PostgresSubscriptionRepository{db: db}
+	ctx := context.Background()
+	userID := uuid.New()
+	minPrice := models.Amount(100)
+
+	filter := SubscriptionFilter{UserID: userID, ServiceNamePrefix: "Net", MinPrice: &minPrice, Sort: "price", Order: "desc", Limit: 1}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM subscriptions WHERE user_id = $1 AND service_name LIKE $2 AND price >= $3")).
+		WithArgs(userID.String(), "Net%", minPrice).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY price DESC, id DESC LIMIT $4")).
+		WithArgs(userID.String(), "Net%", minPrice, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "service_name", "price", "currency", "user_id", "start_date", "end_date", "billing_period", "billing_interval", "version", "created_at", "updated_at"}).
+			AddRow(7, "Netflix", 900, "RUB", userID.String(), "10-2025", nil, "monthly", 1, 1, testTime, testTime).
+			AddRow(3, "Netflix", 500, "RUB", userID.String(), "01-2025", nil, "monthly", 1, 1, testTime, testTime))
+
+	page, err := repo.GetAll(ctx, filter)
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Equal(t, 3, page.Total)
+	assert.NotEmpty(t, page.NextCursor)
+
+	// Следующая страница продолжается после последней записи по (price, id)
+	filter.Cursor = page.NextCursor
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM subscriptions")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(regexp.QuoteMeta("AND (price, id) < ($4::BIGINT, $5::INTEGER) ORDER BY price DESC, id DESC LIMIT $6")).
+		WithArgs(userID.String(), "Net%", minPrice, "900", 7, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "service_name", "price", "currency", "user_id", "start_date", "end_date", "billing_period", "billing_interval", "version", "created_at", "updated_at"}).
+			AddRow(3, "Netflix", 500, "RUB", userID.String(), "01-2025", nil, "monthly", 1, 1, testTime, testTime))
+
+	page, err = repo.GetAll(ctx, filter)
 	assert.NoError(t, err)
-	assert.Len(t, subs, 1)
-	assert.Equal(t, "Netflix", subs[0].ServiceName)
+	assert.Len(t, page.Items, 1)
+	assert.Empty(t, page.NextCursor)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -90,10 +200,10 @@ func TestPostgresSubscriptionRepository_GetByID(t *testing.T) {
 	ctx := context.Background()
 
 	userID := uuid.New()
-	rows := sqlmock.NewRows([]string{"id", "service_name", "price", "user_id", "start_date", "end_date"}).
-		AddRow(1, "Netflix", 500, userID.String(), "10-2025", nil)
+	rows := sqlmock.NewRows([]string{"id", "service_name", "price", "currency", "user_id", "start_date", "end_date", "billing_period", "billing_interval", "version", "created_at", "updated_at"}).
+		AddRow(1, "Netflix", 500, "RUB", userID.String(), "10-2025", nil, "monthly", 1, 3, testTime, testTime)
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, service_name, price, user_id, start_date, end_date FROM subscriptions WHERE id = $1")).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT " + subscriptionColumns + " FROM subscriptions WHERE id = $1")).
 		WithArgs(1).
 		WillReturnRows(rows)
 
@@ -102,6 +212,7 @@ func TestPostgresSubscriptionRepository_GetByID(t *testing.T) {
 	assert.NotNil(t, sub)
 	assert.Equal(t, 1, sub.ID)
 	assert.Equal(t, "Netflix", sub.ServiceName)
+	assert.Equal(t, 3, sub.Version)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -120,11 +231,48 @@ func TestPostgresSubscriptionRepository_Update(t *testing.T) {
 		EndDate:     nil,
 	}
 
-	mock.ExpectExec(regexp.QuoteMeta("UPDATE subscriptions SET service_name=$1, price=$2, user_id=$3, start_date=$4, end_date=$5 WHERE id=$6")).
-		WithArgs(sub.ServiceName, sub.Price, sub.UserID.String(), sub.StartDate, sub.EndDate, 1).
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE subscriptions SET service_name=$1, price=$2, user_id=$3, start_date=TO_DATE($4, 'MM-YYYY'), end_date=TO_DATE($5, 'MM-YYYY'),")).
+		WithArgs(sub.ServiceName, sub.Price, sub.UserID.String(), sub.StartDate, sub.EndDate, "monthly", 1, "RUB", 1, 4).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "created_at", "updated_at"}).AddRow(5, testTime, testTime))
+
+	err = repo.Update(ctx, 1, sub, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, sub.Version)
+
+	// Строка не обновилась, но существует: версия устарела
+	mock.ExpectQuery(regexp.QuoteMeta("version = version + 1, updated_at = NOW() WHERE id=$9 AND version = $10 RETURNING version, created_at, updated_at")).
+		WithArgs(sub.ServiceName, sub.Price, sub.UserID.String(), sub.StartDate, sub.EndDate, "monthly", 1, "RUB", 1, 4).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM subscriptions WHERE id = $1)")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	assert.ErrorIs(t, repo.Update(ctx, 1, sub, 4), ErrVersionMismatch)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresSubscriptionRepository_Patch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	repo := &PostgresSubscriptionRepository{db: db}
+	ctx := context.Background()
+
+	price := models.Amount(700)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE subscriptions SET price = $1, end_date = NULL, version = version + 1, updated_at = NOW() WHERE id = $2")).
+		WithArgs(price, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = repo.Patch(ctx, 1, models.SubscriptionPatch{Price: &price, ClearEndDate: true}, 0)
+	assert.NoError(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE subscriptions SET price = $1, version = version + 1, updated_at = NOW() WHERE id = $2 AND version = $3")).
+		WithArgs(price, 1, 2).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	err = repo.Patch(ctx, 1, models.SubscriptionPatch{Price: &price}, 2)
+	assert.NoError(t, err)
 
-	err = repo.Update(ctx, 1, sub)
+	// Пустой патч не обращается к БД
+	err = repo.Patch(ctx, 1, models.SubscriptionPatch{}, 0)
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -140,7 +288,218 @@ func TestPostgresSubscriptionRepository_Delete(t *testing.T) {
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	err = repo.Delete(ctx, 1)
+	err = repo.Delete(ctx, 1, 0)
+	assert.NoError(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM subscriptions WHERE id = $1 AND version = $2")).
+		WithArgs(1, 3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM subscriptions WHERE id = $1)")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	assert.ErrorIs(t, repo.Delete(ctx, 1, 3), ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresSubscriptionRepository_DomainErrors(t *testing.T) {
+	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
+	defer db.Close()
+	repo := &PostgresSubscriptionRepository{db: db}
+	ctx := context.Background()
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM subscriptions WHERE id = $1")).
+		WithArgs(1).
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.GetByID(ctx, 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM subscriptions WHERE id = $1")).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.Delete(ctx, 2, 0), ErrNotFound)
+
+	sub := &models.Subscription{ServiceName: "Netflix", Price: 500, UserID: uuid.New(), StartDate: "10-2025"}
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO subscriptions")).
+		WillReturnError(&pq.Error{Code: "23514", Message: "violates check constraint"})
+	_, err = repo.Create(ctx, sub)
+	assert.ErrorIs(t, err, ErrValidation)
+
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO subscriptions")).
+		WillReturnError(&pq.Error{Code: "57P01", Message: "terminating connection due to administrator command"})
+	_, err = repo.Create(ctx, sub)
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestPostgresSubscriptionRepository_Suite прогоняет общий набор проверок хранилища на настоящей БД.
+// Запускается, только если задан TEST_DATABASE_URL; таблица subscriptions при этом очищается
+func TestPostgresSubscriptionRepository_Suite(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, migrations.Up(context.Background(), db, "postgres", ""))
+
+	testRepositorySuite(t, func(t *testing.T) SubscriptionRepository {
+		_, err := db.Exec(`TRUNCATE subscriptions RESTART IDENTITY`)
+		require.NoError(t, err)
+		return NewPostgresSubscriptionRepository(db)
+	})
+}
+
+// TestPostgresAPIKeyRepository_Suite прогоняет проверки хранилища API-ключей на настоящей БД, если задан TEST_DATABASE_URL
+func TestPostgresAPIKeyRepository_Suite(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, migrations.Up(context.Background(), db, "postgres", ""))
+	_, err = db.Exec(`TRUNCATE api_keys RESTART IDENTITY`)
+	require.NoError(t, err)
+
+	testAPIKeyRepositorySuite(t, NewPostgresAPIKeyRepository(db))
+}
+
+// TestPostgresIdempotencyRepository_Suite прогоняет проверки хранилища ключей идемпотентности на настоящей БД, если задан TEST_DATABASE_URL
+func TestPostgresIdempotencyRepository_Suite(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, migrations.Up(context.Background(), db, "postgres", ""))
+	_, err = db.Exec(`TRUNCATE idempotency_keys`)
+	require.NoError(t, err)
+
+	testIdempotencyRepositorySuite(t, NewPostgresIdempotencyRepository(db))
+}
+
+func TestPostgresExchangeRateRepository_Upsert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &PostgresExchangeRateRepository{db: db}
+	date := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	prep := mock.ExpectPrepare(regexp.QuoteMeta("INSERT INTO exchange_rates (currency, effective_date, rate) VALUES ($1, $2::DATE, $3)"))
+	prep.ExpectExec().WithArgs("USD", "2025-02-01", 98.25).WillReturnResult(sqlmock.NewResult(0, 1))
+	prep.ExpectExec().WithArgs("EUR", "2025-02-01", -1.0).WillReturnError(&pq.Error{Code: "23514"})
+	mock.ExpectRollback()
+
+	err = repo.Upsert(context.Background(), []models.ExchangeRate{
+		{Currency: "USD", Date: date, Rate: 98.25},
+		{Currency: "EUR", Date: date, Rate: -1},
+	})
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresExchangeRateRepository_ListRange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &PostgresExchangeRateRepository{db: db}
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE currency IN ($1, $2) AND effective_date <= $4::DATE AND effective_date >= COALESCE((SELECT MAX(prev.effective_date)")).
+		WithArgs("EUR", "USD", "2025-02-01", "2025-03-01").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "effective_date", "rate"}).AddRow("USD", "2025-01-01", 100.5))
+
+	rates, err := repo.ListRange(context.Background(), []string{"EUR", "USD"},
+		time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, []models.ExchangeRate{{Currency: "USD", Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Rate: 100.5}}, rates)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+// TestPostgresExchangeRateRepository_Suite прогоняет проверки хранилища курсов валют на настоящей БД, если задан TEST_DATABASE_URL
+func TestPostgresExchangeRateRepository_Suite(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, migrations.Up(context.Background(), db, "postgres", ""))
+	_, err = db.Exec(`TRUNCATE exchange_rates`)
+	require.NoError(t, err)
+
+	testExchangeRateRepositorySuite(t, NewPostgresExchangeRateRepository(db))
+}
+
+func TestPostgresAPIKeyRepository_GetByHash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &PostgresAPIKeyRepository{db: db}
+	created := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "name", "prefix", "key_hash", "scopes", "expires_at", "last_used_at", "created_at", "revoked_at"}).
+		AddRow(3, "billing", "rsk_abcd", "hash", "{subscriptions:read,reports:read}", nil, created, created, nil)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, prefix, key_hash, scopes")).
+		WithArgs("hash").
+		WillReturnRows(rows)
+
+	key, err := repo.GetByHash(context.Background(), "hash")
+	require.NoError(t, err)
+	assert.Equal(t, 3, key.ID)
+	assert.Equal(t, []string{"subscriptions:read", "reports:read"}, key.Scopes)
+	assert.Nil(t, key.ExpiresAt)
+	assert.Equal(t, created, *key.LastUsedAt)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, prefix, key_hash, scopes")).WillReturnError(sql.ErrNoRows)
+	_, err = repo.GetByHash(context.Background(), "other")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresSubscriptionRepository_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	repo := &PostgresSubscriptionRepository{db: db}
+	ctx := context.Background()
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM subscriptions")).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT TO_CHAR(month, 'MM-YYYY')")).WillReturnError(&pq.Error{Code: "57P01"})
+
+	assert.NoError(t, repo.Delete(ctx, 1, 0))
+	_, err = repo.GetCosts(ctx, CostFilter{Start: "01-2025", End: "12-2025"})
+	assert.ErrorIs(t, err, ErrUnavailable)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	attrs := func(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+		m := map[attribute.Key]attribute.Value{}
+		for _, kv := range span.Attributes() {
+			m[kv.Key] = kv.Value
+		}
+		return m
+	}
+
+	del := spans[0]
+	assert.Equal(t, "PostgresSubscriptionRepository.Delete", del.Name())
+	assert.Equal(t, "DELETE FROM subscriptions WHERE id = $1", attrs(del)["db.query.text"].AsString())
+	assert.Equal(t, int64(1), attrs(del)[rowsAffectedKey].AsInt64())
+	assert.Equal(t, codes.Unset, del.Status().Code)
+
+	sum := spans[1]
+	assert.Equal(t, "PostgresSubscriptionRepository.GetCosts", sum.Name())
+	assert.Equal(t, codes.Error, sum.Status().Code)
+	assert.Len(t, sum.Events(), 1) // записанная ошибка
+}
@@ -0,0 +1,526 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"rest-service/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLiteSubscriptionRepository реализует интерфейс работы с подписками через SQLite.
+// Даты хранятся как текст YYYY-MM-01, наружу отдаются в формате MM-YYYY
+type SQLiteSubscriptionRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteSubscriptionRepository(db *sql.DB) SubscriptionRepository {
+	return &SQLiteSubscriptionRepository{db: db}
+}
+
+const (
+	sqliteSubscriptionColumns = `id, service_name, price, currency, user_id, strftime('%m-%Y', start_date), strftime('%m-%Y', end_date),
+    billing_period, billing_interval, version, created_at, updated_at`
+
+	// sqliteOpenEnd — дата окончания бессрочной подписки при сравнениях и сортировке
+	sqliteOpenEnd = "9999-12-01"
+)
+
+// sqliteSortColumns — SQL-выражения для колонок сортировки
+var sqliteSortColumns = map[string]string{
+	"id":           "id",
+	"service_name": "service_name",
+	"price":        "price",
+	"user_id":      "user_id",
+	"start_date":   "start_date",
+	"end_date":     "COALESCE(end_date, '" + sqliteOpenEnd + "')",
+}
+
+// isoMonth переводит MM-YYYY в хранимый формат YYYY-MM-01
+func isoMonth(month string) (string, error) {
+	t, err := models.ParseMonth(month)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+	return t.Format("2006-01-02"), nil
+}
+
+// isoMonthPtr переводит nullable MM-YYYY в хранимый формат
+func isoMonthPtr(month *string) (interface{}, error) {
+	if month == nil {
+		return nil, nil
+	}
+	return isoMonth(*month)
+}
+
+// sqliteTimeLayout — RFC 3339 с дробной частью фиксированной длины, чтобы время можно было сравнивать как строки
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// sqliteTime переводит время в хранимый формат
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+// sqliteTimePtr переводит nullable время в хранимый формат
+func sqliteTimePtr(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return sqliteTime(*t)
+}
+
+// parseSQLiteTime разбирает nullable время, прочитанное из БД
+func parseSQLiteTime(s sql.NullString) (*time.Time, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// mapSQLiteError переводит ошибку драйвера SQLite в доменную ошибку
+func mapSQLiteError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return fmt.Errorf("%w: %w", ErrConflict, err)
+		case sqlite3.SQLITE_CONSTRAINT_CHECK, sqlite3.SQLITE_CONSTRAINT_NOTNULL:
+			return fmt.Errorf("%w: %w", ErrValidation, err)
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED, sqlite3.SQLITE_CANTOPEN, sqlite3.SQLITE_FULL:
+			return fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+	}
+	return err
+}
+
+// GetCosts подсчитывает стоимость подписок по месяцам периода, группам и валютам, так же как GetCosts в PostgreSQL
+func (r *SQLiteSubscriptionRepository) GetCosts(ctx context.Context, filter CostFilter) ([]MonthlyCost, error) {
+	from, err := isoMonth(filter.Start)
+	if err != nil {
+		return nil, err
+	}
+	to, err := isoMonth(filter.End)
+	if err != nil {
+		return nil, err
+	}
+
+	var args queryArgs
+	fromArg, toArg := args.add(from), args.add(to)
+	conds := costConditions(filter, &args)
+	group, err := costGroupSQL(filter.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Месяцы периода перебираются рекурсивным CTE вместо generate_series
+	query := `WITH RECURSIVE months(month) AS (
+                  SELECT ` + fromArg + `
+                  UNION ALL
+                  SELECT date(month, '+1 month') FROM months WHERE month < ` + toArg + `
+              )
+              SELECT strftime('%m-%Y', month), grp, currency,
+                     SUM(price * ((end_offset + step - 1) / step - (start_offset + step - 1) / step)),
+                     CAST(ROUND(SUM(monthly)) AS INTEGER), COUNT(*)
+              FROM (
+                  SELECT month, ` + group + ` AS grp, currency, price,
+                         ` + billingStepSQL + ` AS step,
+                         ` + monthlyEquivalentSQL + ` AS monthly,
+                         CASE WHEN billing_period = 'weekly' THEN CAST(julianday(month) - julianday(start_date) AS INTEGER)
+                              ELSE ` + sqliteMonthsDiff("start_date", "month") + ` END AS start_offset,
+                         CASE WHEN billing_period = 'weekly' THEN CAST(julianday(date(month, '+1 month')) - julianday(start_date) AS INTEGER)
+                              ELSE ` + sqliteMonthsDiff("start_date", "month") + ` + 1 END AS end_offset
+                  FROM months
+                  JOIN subscriptions ON start_date <= month AND (end_date IS NULL OR end_date >= month)` + whereClause(conds) + `
+              ) AS charges
+              GROUP BY month, grp, currency
+              ORDER BY month, grp, currency`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, mapSQLiteError(err)
+	}
+	defer rows.Close()
+	return scanMonthlyCosts(rows, mapSQLiteError)
+}
+
+// sqliteMonthsDiff возвращает SQL-выражение для числа месяцев от даты from до даты to
+func sqliteMonthsDiff(from, to string) string {
+	return fmt.Sprintf("((CAST(strftime('%%Y', %[2]s) AS INTEGER) - CAST(strftime('%%Y', %[1]s) AS INTEGER)) * 12"+
+		" + CAST(strftime('%%m', %[2]s) AS INTEGER) - CAST(strftime('%%m', %[1]s) AS INTEGER))", from, to)
+}
+
+// CountSubscriptions подсчитывает подписки, активные хотя бы в одном месяце периода, так же как CountSubscriptions в PostgreSQL
+func (r *SQLiteSubscriptionRepository) CountSubscriptions(ctx context.Context, filter CostFilter) ([]SubscriptionCount, error) {
+	from, err := isoMonth(filter.Start)
+	if err != nil {
+		return nil, err
+	}
+	to, err := isoMonth(filter.End)
+	if err != nil {
+		return nil, err
+	}
+	group, err := costGroupSQL(filter.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	var args queryArgs
+	conds := append([]string{
+		"start_date <= " + args.add(to),
+		"(end_date IS NULL OR end_date >= " + args.add(from) + ")",
+	}, costConditions(filter, &args)...)
+	query := `SELECT ` + group + ` AS grp, COUNT(*) FROM subscriptions` + whereClause(conds) + ` GROUP BY grp ORDER BY grp`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, mapSQLiteError(err)
+	}
+	defer rows.Close()
+	return scanSubscriptionCounts(rows, mapSQLiteError)
+}
+
+// GetServiceStats возвращает по каждому сервису и валюте число подписок, активных в месяце month, и сумму их месячных эквивалентов цен
+func (r *SQLiteSubscriptionRepository) GetServiceStats(ctx context.Context, month string) ([]ServiceStats, error) {
+	m, err := isoMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT service_name, currency, COUNT(*), CAST(ROUND(SUM(` + monthlyEquivalentSQL + `)) AS INTEGER)
+              FROM subscriptions
+              WHERE start_date <= $1 AND (end_date IS NULL OR end_date >= $1)
+              GROUP BY service_name, currency
+              ORDER BY service_name, currency`
+	rows, err := r.db.QueryContext(ctx, query, m)
+	if err != nil {
+		return nil, mapSQLiteError(err)
+	}
+	defer rows.Close()
+	return scanServiceStats(rows, mapSQLiteError)
+}
+
+// Create добавляет новую подписку и возвращает сгенерированный ID
+func (r *SQLiteSubscriptionRepository) Create(ctx context.Context, sub *models.Subscription) (int, error) {
+	start, err := isoMonth(sub.StartDate)
+	if err != nil {
+		return 0, err
+	}
+	end, err := isoMonthPtr(sub.EndDate)
+	if err != nil {
+		return 0, err
+	}
+
+	sub.SetDefaults()
+	now := time.Now().UTC()
+	query := `INSERT INTO subscriptions (service_name, price, user_id, start_date, end_date, billing_period, billing_interval, currency, created_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9) RETURNING id, version`
+	err = r.db.QueryRowContext(ctx, query, sub.ServiceName, sub.Price, sub.UserID.String(), start, end,
+		sub.BillingPeriod, sub.BillingInterval, sub.Currency, sqliteTime(now)).Scan(&sub.ID, &sub.Version)
+	if err != nil {
+		return 0, mapSQLiteError(err)
+	}
+	sub.CreatedAt, sub.UpdatedAt = now, now
+	return sub.ID, nil
+}
+
+// sqliteListConditions строит условия WHERE по фильтрам списка (без учёта курсора)
+func sqliteListConditions(f SubscriptionFilter, args *queryArgs) ([]string, error) {
+	var conds []string
+	if f.UserID != uuid.Nil {
+		conds = append(conds, "user_id = "+args.add(f.UserID.String()))
+	}
+	if f.ServiceName != "" {
+		conds = append(conds, "service_name = "+args.add(f.ServiceName))
+	}
+	if f.ServiceNamePrefix != "" {
+		// LIKE в SQLite нечувствителен к регистру, поэтому сравниваем начало строки явно
+		prefix := args.add(f.ServiceNamePrefix)
+		conds = append(conds, "substr(service_name, 1, length("+prefix+")) = "+prefix)
+	}
+	if f.MinPrice != nil {
+		conds = append(conds, "price >= "+args.add(*f.MinPrice))
+	}
+	if f.MaxPrice != nil {
+		conds = append(conds, "price <= "+args.add(*f.MaxPrice))
+	}
+	if f.ActiveIn != "" {
+		month, err := isoMonth(f.ActiveIn)
+		if err != nil {
+			return nil, err
+		}
+		arg := args.add(month)
+		conds = append(conds, "start_date <= "+arg+" AND (end_date IS NULL OR end_date >= "+arg+")")
+	}
+	if !f.CreatedSince.IsZero() {
+		conds = append(conds, "created_at >= "+args.add(sqliteTime(f.CreatedSince)))
+	}
+	if !f.UpdatedSince.IsZero() {
+		conds = append(conds, "updated_at >= "+args.add(sqliteTime(f.UpdatedSince)))
+	}
+	return conds, nil
+}
+
+// GetAll возвращает страницу подписок с учётом фильтров, сортировки и пагинации
+func (r *SQLiteSubscriptionRepository) GetAll(ctx context.Context, filter SubscriptionFilter) (*SubscriptionPage, error) {
+	if err := filter.Normalize(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+
+	var countArgs queryArgs
+	countConds, err := sqliteListConditions(filter, &countArgs)
+	if err != nil {
+		return nil, err
+	}
+	page := &SubscriptionPage{Items: []models.Subscription{}}
+	countQuery := `SELECT COUNT(*) FROM subscriptions` + whereClause(countConds)
+	if err := r.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&page.Total); err != nil {
+		return nil, mapSQLiteError(err)
+	}
+
+	var args queryArgs
+	conds, err := sqliteListConditions(filter, &args)
+	if err != nil {
+		return nil, err
+	}
+	column := sqliteSortColumns[filter.Sort]
+	direction, cmp := "ASC", ">"
+	if filter.Order == SortDesc {
+		direction, cmp = "DESC", "<"
+	}
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+		}
+		value, err := sqliteCursorValue(c)
+		if err != nil {
+			return nil, err
+		}
+		// Keyset-пагинация: продолжаем строго после последней записи предыдущей страницы
+		conds = append(conds, fmt.Sprintf("(%s, id) %s (%s, %s)", column, cmp, args.add(value), args.add(c.ID)))
+	}
+
+	query := `SELECT ` + sqliteSubscriptionColumns + ` FROM subscriptions` + whereClause(conds) +
+		fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %s", column, direction, direction, args.add(filter.Limit+1))
+	if filter.Cursor == "" && filter.Offset > 0 {
+		query += " OFFSET " + args.add(filter.Offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, mapSQLiteError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		sub, err := scanSQLiteSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, *sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, mapSQLiteError(err)
+	}
+
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	if len(page.Items) > filter.Limit {
+		page.Items = page.Items[:filter.Limit]
+		page.NextCursor = encodeCursor(filter, page.Items[filter.Limit-1])
+	}
+	return page, nil
+}
+
+// sqliteCursorValue переводит значение курсора в значение, сравнимое с колонкой сортировки
+func sqliteCursorValue(c cursor) (interface{}, error) {
+	switch c.Sort {
+	case "id", "price":
+		n, err := strconv.ParseInt(c.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrValidation, ErrInvalidCursor)
+		}
+		return n, nil
+	case "start_date":
+		return isoMonth(c.Value)
+	case "end_date":
+		if c.Value == "" {
+			return sqliteOpenEnd, nil
+		}
+		return isoMonth(c.Value)
+	default:
+		return c.Value, nil
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSQLiteSubscription(row rowScanner) (*models.Subscription, error) {
+	var sub models.Subscription
+	var userID string
+	var createdAt, updatedAt sql.NullString
+	err := row.Scan(&sub.ID, &sub.ServiceName, &sub.Price, &sub.Currency, &userID, &sub.StartDate, &sub.EndDate,
+		&sub.BillingPeriod, &sub.BillingInterval, &sub.Version, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, mapSQLiteError(err)
+	}
+	sub.UserID, err = uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range []struct {
+		src sql.NullString
+		dst *time.Time
+	}{{createdAt, &sub.CreatedAt}, {updatedAt, &sub.UpdatedAt}} {
+		parsed, err := parseSQLiteTime(t.src)
+		if err != nil {
+			return nil, err
+		}
+		if parsed != nil {
+			*t.dst = *parsed
+		}
+	}
+	return &sub, nil
+}
+
+// GetByID возвращает подписку по ID
+func (r *SQLiteSubscriptionRepository) GetByID(ctx context.Context, id int) (*models.Subscription, error) {
+	query := `SELECT ` + sqliteSubscriptionColumns + ` FROM subscriptions WHERE id = $1`
+	return scanSQLiteSubscription(r.db.QueryRowContext(ctx, query, id))
+}
+
+// Update изменяет данные подписки по ID
+func (r *SQLiteSubscriptionRepository) Update(ctx context.Context, id int, sub *models.Subscription, version int) error {
+	start, err := isoMonth(sub.StartDate)
+	if err != nil {
+		return err
+	}
+	end, err := isoMonthPtr(sub.EndDate)
+	if err != nil {
+		return err
+	}
+
+	sub.SetDefaults()
+	now := time.Now().UTC()
+	args := queryArgs{sub.ServiceName, sub.Price, sub.UserID.String(), start, end, sub.BillingPeriod, sub.BillingInterval, sub.Currency, sqliteTime(now), id}
+	query := `UPDATE subscriptions SET service_name=$1, price=$2, user_id=$3, start_date=$4, end_date=$5,
+              billing_period=$6, billing_interval=$7, currency=$8, version = version + 1, updated_at = $9 WHERE id=$10` + versionCondition(version, &args) + ` RETURNING version, created_at`
+	var newVersion int
+	var createdAt sql.NullString
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&newVersion, &createdAt); err != nil {
+		return r.conditionalError(ctx, id, version, mapSQLiteError(err))
+	}
+	created, err := parseSQLiteTime(createdAt)
+	if err != nil {
+		return err
+	}
+	sub.ID, sub.Version, sub.UpdatedAt = id, newVersion, now
+	if created != nil {
+		sub.CreatedAt = *created
+	}
+	return nil
+}
+
+// Patch изменяет только переданные в патче поля подписки
+func (r *SQLiteSubscriptionRepository) Patch(ctx context.Context, id int, patch models.SubscriptionPatch, version int) error {
+	if patch.IsEmpty() {
+		return nil
+	}
+
+	var args queryArgs
+	var set []string
+	if patch.ServiceName != nil {
+		set = append(set, "service_name = "+args.add(*patch.ServiceName))
+	}
+	if patch.Price != nil {
+		set = append(set, "price = "+args.add(*patch.Price))
+	}
+	if patch.UserID != nil {
+		set = append(set, "user_id = "+args.add(patch.UserID.String()))
+	}
+	if patch.StartDate != nil {
+		start, err := isoMonth(*patch.StartDate)
+		if err != nil {
+			return err
+		}
+		set = append(set, "start_date = "+args.add(start))
+	}
+	if patch.ClearEndDate {
+		set = append(set, "end_date = NULL")
+	} else if patch.EndDate != nil {
+		end, err := isoMonth(*patch.EndDate)
+		if err != nil {
+			return err
+		}
+		set = append(set, "end_date = "+args.add(end))
+	}
+	if patch.BillingPeriod != nil {
+		set = append(set, "billing_period = "+args.add(*patch.BillingPeriod))
+	}
+	if patch.BillingInterval != nil {
+		set = append(set, "billing_interval = "+args.add(*patch.BillingInterval))
+	}
+	if patch.Currency != nil {
+		set = append(set, "currency = "+args.add(*patch.Currency))
+	}
+
+	set = append(set, "version = version + 1", "updated_at = "+args.add(sqliteTime(time.Now())))
+
+	query := `UPDATE subscriptions SET ` + strings.Join(set, ", ") + ` WHERE id = ` + args.add(id) + versionCondition(version, &args)
+	result, err := r.db.ExecContext(ctx, query, args...)
+	return r.conditionalError(ctx, id, version, checkSQLiteAffected(result, err))
+}
+
+// Delete удаляет подписку по ID
+func (r *SQLiteSubscriptionRepository) Delete(ctx context.Context, id int, version int) error {
+	args := queryArgs{id}
+	result, err := r.db.ExecContext(ctx, `DELETE FROM subscriptions WHERE id = $1`+versionCondition(version, &args), args...)
+	return r.conditionalError(ctx, id, version, checkSQLiteAffected(result, err))
+}
+
+// conditionalError уточняет ErrNotFound условного изменения: если подписка существует, значит не совпала версия
+func (r *SQLiteSubscriptionRepository) conditionalError(ctx context.Context, id, version int, err error) error {
+	if version == 0 || !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM subscriptions WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return mapSQLiteError(err)
+	}
+	if exists {
+		return ErrVersionMismatch
+	}
+	return ErrNotFound
+}
+
+// checkSQLiteAffected возвращает ErrNotFound, если запрос не затронул ни одной строки
+func checkSQLiteAffected(result sql.Result, err error) error {
+	if err != nil {
+		return mapSQLiteError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return mapSQLiteError(err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
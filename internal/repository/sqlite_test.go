@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"rest-service/migrations"
+)
+
+func newSQLiteDB(t *testing.T) *sql.DB {
+	// Как и в main, занятая БД ожидается, а не сразу возвращает SQLITE_BUSY
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "subscriptions.db")+"?_pragma=busy_timeout(5000)")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, migrations.Up(context.Background(), db, "sqlite", ""))
+	return db
+}
+
+func TestSQLiteSubscriptionRepository(t *testing.T) {
+	testRepositorySuite(t, func(t *testing.T) SubscriptionRepository {
+		return NewSQLiteSubscriptionRepository(newSQLiteDB(t))
+	})
+}
+
+func TestSQLiteAPIKeyRepository(t *testing.T) {
+	testAPIKeyRepositorySuite(t, NewSQLiteAPIKeyRepository(newSQLiteDB(t)))
+}
+
+func TestSQLiteIdempotencyRepository(t *testing.T) {
+	testIdempotencyRepositorySuite(t, NewSQLiteIdempotencyRepository(newSQLiteDB(t)))
+}
+
+func TestSQLiteExchangeRateRepository(t *testing.T) {
+	testExchangeRateRepositorySuite(t, NewSQLiteExchangeRateRepository(newSQLiteDB(t)))
+}
@@ -3,15 +3,76 @@ package repository
 import (
 	"context"
 	"rest-service/internal/models"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// SubscriptionRepository — хранилище подписок.
+// Методы возвращают ErrNotFound, если подписка с указанным ID не существует,
+// а ошибки хранилища оборачивают в ErrConflict, ErrValidation или ErrUnavailable.
+// Update, Patch и Delete с version > 0 изменяют подписку, только если её текущая версия равна version,
+// и иначе возвращают ErrVersionMismatch; version = 0 — без проверки. Каждое изменение увеличивает версию на 1
 type SubscriptionRepository interface {
 	Create(ctx context.Context, sub *models.Subscription) (int, error)
-	GetAll(ctx context.Context) ([]models.Subscription, error)
+	GetAll(ctx context.Context, filter SubscriptionFilter) (*SubscriptionPage, error)
 	GetByID(ctx context.Context, id int) (*models.Subscription, error)
-	Update(ctx context.Context, id int, sub *models.Subscription) error
-	Delete(ctx context.Context, id int) error
-	GetSum(ctx context.Context, start, end string, userID uuid.UUID, serviceName string) (int, error)
+	Update(ctx context.Context, id int, sub *models.Subscription, version int) error // записывает в sub новую версию
+	Patch(ctx context.Context, id int, patch models.SubscriptionPatch, version int) error
+	Delete(ctx context.Context, id int, version int) error
+	GetCosts(ctx context.Context, filter CostFilter) ([]MonthlyCost, error)
+	GetServiceStats(ctx context.Context, month string) ([]ServiceStats, error)
+	CountSubscriptions(ctx context.Context, filter CostFilter) ([]SubscriptionCount, error)
+}
+
+// CostGroup — разбивка стоимости подписок внутри месяца
+type CostGroup string
+
+const (
+	CostGroupNone    CostGroup = ""
+	CostGroupService CostGroup = "service_name"
+	CostGroupUser    CostGroup = "user_id"
+)
+
+// Valid сообщает, поддерживается ли разбивка
+func (g CostGroup) Valid() bool {
+	switch g {
+	case CostGroupNone, CostGroupService, CostGroupUser:
+		return true
+	}
+	return false
+}
+
+// CostFilter — период и фильтры подсчёта стоимости подписок
+type CostFilter struct {
+	Start, End   string      // первый и последний месяц периода, MM-YYYY
+	UserIDs      []uuid.UUID // пустой — все пользователи
+	ServiceNames []string    // пустой — все сервисы
+	GroupBy      CostGroup   // разбивка по сервисам или пользователям, по умолчанию без неё
+}
+
+// MonthlyCost — стоимость подписок в одной валюте за один месяц периода.
+// Первое списание по подписке приходится на первое число месяца start_date,
+// следующие — через каждый период оплаты, пока подписка активна. Charged и Normalized всегда в одной валюте.
+// GetCosts возвращает строки по возрастанию месяца, группы и валюты
+type MonthlyCost struct {
+	Month      time.Time    // первое число месяца, UTC
+	Group      string       // имя сервиса или ID пользователя при разбивке GroupBy, иначе пустая строка
+	Charged    models.Money // сумма списаний в этом месяце
+	Normalized models.Money // сумма месячных эквивалентов цен подписок, активных в этом месяце, с точностью до сотых
+	Active     int          // число подписок, активных в этом месяце
+}
+
+// SubscriptionCount — число подписок группы, активных хотя бы в одном месяце периода.
+// CountSubscriptions возвращает строки по возрастанию группы
+type SubscriptionCount struct {
+	Group string // имя сервиса или ID пользователя при разбивке GroupBy, иначе пустая строка
+	Count int
+}
+
+// ServiceStats — сводка по подпискам одного сервиса в одной валюте, активным в заданном месяце
+type ServiceStats struct {
+	ServiceName  string
+	ActiveCount  int          // число активных подписок
+	MonthlySpend models.Money // сумма месячных эквивалентов цен активных подписок
 }
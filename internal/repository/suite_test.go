@@ -0,0 +1,345 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rest-service/internal/models"
+)
+
+// testRepositorySuite проверяет поведение реализации SubscriptionRepository.
+// Одни и те же проверки выполняются для всех хранилищ, чтобы их семантика совпадала;
+// newRepo должен возвращать пустое хранилище с последовательностью ID, начинающейся с 1
+func testRepositorySuite(t *testing.T, newRepo func(t *testing.T) SubscriptionRepository) {
+	t.Run("CRUD", func(t *testing.T) { testRepositoryCRUD(t, newRepo(t)) })
+	t.Run("GetCosts", func(t *testing.T) { testRepositoryGetCosts(t, newRepo(t)) })
+	t.Run("GetCostsGrouped", func(t *testing.T) { testRepositoryGetCostsGrouped(t, newRepo(t)) })
+	t.Run("GetAll", func(t *testing.T) { testRepositoryGetAll(t, newRepo(t)) })
+	t.Run("GetServiceStats", func(t *testing.T) { testRepositoryGetServiceStats(t, newRepo(t)) })
+	t.Run("BillingPeriods", func(t *testing.T) { testRepositoryBillingPeriods(t, newRepo(t)) })
+	t.Run("Timestamps", func(t *testing.T) { testRepositoryTimestamps(t, newRepo(t)) })
+}
+
+func testRepositoryTimestamps(t *testing.T, repo SubscriptionRepository) {
+	ctx := context.Background()
+	before := time.Now().Add(-time.Minute)
+
+	first := &models.Subscription{ServiceName: "Netflix", Price: 500, UserID: uuid.New(), StartDate: "10-2025"}
+	_, err := repo.Create(ctx, first)
+	require.NoError(t, err)
+	assert.True(t, first.CreatedAt.After(before))
+	assert.True(t, first.CreatedAt.Equal(first.UpdatedAt))
+	_, err = repo.Create(ctx, &models.Subscription{ServiceName: "Spotify", Price: 200, UserID: uuid.New(), StartDate: "01-2025"})
+	require.NoError(t, err)
+
+	price := models.Amount(600)
+	require.NoError(t, repo.Patch(ctx, 1, models.SubscriptionPatch{Price: &price}, 0))
+	patched, err := repo.GetByID(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, patched.CreatedAt.Equal(first.CreatedAt))
+	assert.True(t, patched.UpdatedAt.After(first.UpdatedAt))
+
+	// Инкрементальная синхронизация: только изменённые с момента последнего изменения
+	page, err := repo.GetAll(ctx, SubscriptionFilter{UpdatedSince: patched.UpdatedAt})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, 1, page.Items[0].ID)
+	assert.True(t, page.Items[0].UpdatedAt.Equal(patched.UpdatedAt))
+
+	page, err = repo.GetAll(ctx, SubscriptionFilter{CreatedSince: before})
+	require.NoError(t, err)
+	assert.Equal(t, 2, page.Total)
+	page, err = repo.GetAll(ctx, SubscriptionFilter{CreatedSince: time.Now().Add(time.Minute)})
+	require.NoError(t, err)
+	assert.Equal(t, 0, page.Total)
+
+	patched.ServiceName = "Netflix Premium"
+	require.NoError(t, repo.Update(ctx, 1, patched, 0))
+	assert.True(t, patched.CreatedAt.Equal(first.CreatedAt))
+	got, err := repo.GetByID(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, got.UpdatedAt.Equal(patched.UpdatedAt))
+	assert.True(t, got.UpdatedAt.After(first.UpdatedAt))
+}
+
+func testRepositoryCRUD(t *testing.T, repo SubscriptionRepository) {
+	ctx := context.Background()
+	end := "12-2025"
+
+	sub := &models.Subscription{ServiceName: "Netflix", Price: 500, UserID: uuid.New(), StartDate: "10-2025", EndDate: &end}
+	id, err := repo.Create(ctx, sub)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, id)
+	assert.Equal(t, 1, sub.Version)
+
+	id2, err := repo.Create(ctx, &models.Subscription{ServiceName: "Spotify", Price: 200, UserID: uuid.New(), StartDate: "01-2025"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, id2)
+
+	// Изменение исходной структуры не влияет на хранимые данные
+	end = "01-2026"
+	got, err := repo.GetByID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "12-2025", *got.EndDate)
+
+	assert.Equal(t, 1, got.Version)
+	assert.Equal(t, models.BaseCurrency, got.Currency)
+
+	price, currency := models.Amount(600), "USD"
+	assert.NoError(t, repo.Patch(ctx, 1, models.SubscriptionPatch{Price: &price, Currency: &currency, ClearEndDate: true}, 0))
+	got, _ = repo.GetByID(ctx, 1)
+	assert.Equal(t, models.Amount(600), got.Price)
+	assert.Equal(t, "USD", got.Currency)
+	assert.Nil(t, got.EndDate)
+	assert.Equal(t, 2, got.Version)
+
+	bad := "01-2020"
+	assert.ErrorIs(t, repo.Patch(ctx, 1, models.SubscriptionPatch{EndDate: &bad}, 0), ErrValidation)
+	assert.ErrorIs(t, repo.Patch(ctx, 1, models.SubscriptionPatch{Price: &price}, 1), ErrVersionMismatch)
+
+	// Условное изменение выполняется только на текущей версии
+	got.ServiceName = "Netflix Premium"
+	assert.ErrorIs(t, repo.Update(ctx, 1, got, 1), ErrVersionMismatch)
+	assert.NoError(t, repo.Update(ctx, 1, got, 2))
+	assert.Equal(t, 3, got.Version)
+	got, _ = repo.GetByID(ctx, 1)
+	assert.Equal(t, "Netflix Premium", got.ServiceName)
+	assert.Equal(t, 3, got.Version)
+
+	assert.ErrorIs(t, repo.Delete(ctx, 1, 2), ErrVersionMismatch)
+	assert.NoError(t, repo.Delete(ctx, 1, 3))
+	_, err = repo.GetByID(ctx, 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, repo.Delete(ctx, 1, 0), ErrNotFound)
+	assert.ErrorIs(t, repo.Delete(ctx, 1, 3), ErrNotFound)
+	assert.ErrorIs(t, repo.Update(ctx, 1, got, 0), ErrNotFound)
+}
+
+// totalCost складывает помесячную стоимость за период во всех валютах
+func totalCost(t *testing.T, repo SubscriptionRepository, filter CostFilter) (charged, normalized models.Amount) {
+	t.Helper()
+	costs, err := repo.GetCosts(context.Background(), filter)
+	require.NoError(t, err)
+	for _, c := range costs {
+		charged += c.Charged.Amount
+		normalized += c.Normalized.Amount
+	}
+	return charged, normalized
+}
+
+func testRepositoryGetCosts(t *testing.T, repo SubscriptionRepository) {
+	ctx := context.Background()
+	userID := uuid.New()
+	end := "03-2024"
+
+	// Активна весь 2024 год: 12 месяцев по 400
+	repo.Create(ctx, &models.Subscription{ServiceName: "Yandex Plus", Price: 400, UserID: userID, StartDate: "12-2023"})
+	// Пересекается с 2024 годом на 3 месяца
+	repo.Create(ctx, &models.Subscription{ServiceName: "Yandex Plus", Price: 100, UserID: userID, StartDate: "05-2023", EndDate: &end})
+	// Не пересекается с периодом
+	repo.Create(ctx, &models.Subscription{ServiceName: "Yandex Plus", Price: 999, UserID: userID, StartDate: "01-2025"})
+	// Другой пользователь
+	repo.Create(ctx, &models.Subscription{ServiceName: "Yandex Plus", Price: 999, UserID: uuid.New(), StartDate: "01-2024"})
+
+	// В долларах, пересекается с периодом на 2 месяца
+	repo.Create(ctx, &models.Subscription{ServiceName: "Yandex Plus", Price: 5, Currency: "USD", UserID: userID, StartDate: "12-2023", EndDate: &end})
+
+	charged, normalized := totalCost(t, repo, CostFilter{Start: "01-2024", End: "12-2024", UserIDs: []uuid.UUID{userID}, ServiceNames: []string{"Yandex Plus"}})
+	assert.Equal(t, models.Amount(400*12+100*3+5*3), charged)
+	assert.Equal(t, models.Amount(400*12+100*3+5*3), normalized)
+
+	costs, err := repo.GetCosts(ctx, CostFilter{Start: "12-2023", End: "01-2024"})
+	require.NoError(t, err)
+	month := func(m time.Month, y int) time.Time { return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC) }
+	assert.Equal(t, []MonthlyCost{
+		{Month: month(time.December, 2023), Charged: models.Money{Amount: 400 + 100, Currency: "RUB"}, Normalized: models.Money{Amount: 400 + 100, Currency: "RUB"}, Active: 2},
+		{Month: month(time.December, 2023), Charged: models.Money{Amount: 5, Currency: "USD"}, Normalized: models.Money{Amount: 5, Currency: "USD"}, Active: 1},
+		{Month: month(time.January, 2024), Charged: models.Money{Amount: 400 + 100 + 999, Currency: "RUB"}, Normalized: models.Money{Amount: 400 + 100 + 999, Currency: "RUB"}, Active: 3},
+		{Month: month(time.January, 2024), Charged: models.Money{Amount: 5, Currency: "USD"}, Normalized: models.Money{Amount: 5, Currency: "USD"}, Active: 1},
+	}, costs)
+
+	// Месяцы без активных подписок в результат не попадают
+	costs, err = repo.GetCosts(ctx, CostFilter{Start: "01-2020", End: "12-2020"})
+	require.NoError(t, err)
+	assert.Empty(t, costs)
+}
+
+func testRepositoryGetCostsGrouped(t *testing.T, repo SubscriptionRepository) {
+	ctx := context.Background()
+	alice, bob := uuid.MustParse("00000000-0000-0000-0000-00000000000a"), uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+	end := "01-2025"
+	repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: 500, UserID: alice, StartDate: "01-2025", EndDate: &end})
+	repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: 700, UserID: bob, StartDate: "01-2025"})
+	repo.Create(ctx, &models.Subscription{ServiceName: "Spotify", Price: 200, UserID: alice, StartDate: "02-2025"})
+
+	month := func(m time.Month) time.Time { return time.Date(2025, m, 1, 0, 0, 0, 0, time.UTC) }
+	rub := func(a models.Amount) models.Money { return models.Money{Amount: a, Currency: "RUB"} }
+
+	costs, err := repo.GetCosts(ctx, CostFilter{Start: "01-2025", End: "02-2025", GroupBy: CostGroupService})
+	require.NoError(t, err)
+	assert.Equal(t, []MonthlyCost{
+		{Month: month(time.January), Group: "Netflix", Charged: rub(1200), Normalized: rub(1200), Active: 2},
+		{Month: month(time.February), Group: "Netflix", Charged: rub(700), Normalized: rub(700), Active: 1},
+		{Month: month(time.February), Group: "Spotify", Charged: rub(200), Normalized: rub(200), Active: 1},
+	}, costs)
+
+	costs, err = repo.GetCosts(ctx, CostFilter{Start: "01-2025", End: "02-2025", ServiceNames: []string{"Netflix"}, GroupBy: CostGroupUser})
+	require.NoError(t, err)
+	assert.Equal(t, []MonthlyCost{
+		{Month: month(time.January), Group: alice.String(), Charged: rub(500), Normalized: rub(500), Active: 1},
+		{Month: month(time.January), Group: bob.String(), Charged: rub(700), Normalized: rub(700), Active: 1},
+		{Month: month(time.February), Group: bob.String(), Charged: rub(700), Normalized: rub(700), Active: 1},
+	}, costs)
+
+	_, err = repo.GetCosts(ctx, CostFilter{Start: "01-2025", End: "02-2025", GroupBy: "currency"})
+	assert.ErrorIs(t, err, ErrValidation)
+
+	// Подписка считается один раз за период, в котором она активна хотя бы месяц
+	counts, err := repo.CountSubscriptions(ctx, CostFilter{Start: "01-2025", End: "03-2025", GroupBy: CostGroupService})
+	require.NoError(t, err)
+	assert.Equal(t, []SubscriptionCount{{Group: "Netflix", Count: 2}, {Group: "Spotify", Count: 1}}, counts)
+
+	counts, err = repo.CountSubscriptions(ctx, CostFilter{Start: "02-2025", End: "03-2025", UserIDs: []uuid.UUID{alice, bob}})
+	require.NoError(t, err)
+	assert.Equal(t, []SubscriptionCount{{Count: 2}}, counts)
+
+	counts, err = repo.CountSubscriptions(ctx, CostFilter{Start: "02-2025", End: "12-2025", UserIDs: []uuid.UUID{alice}, ServiceNames: []string{"Netflix", "Spotify"}, GroupBy: CostGroupUser})
+	require.NoError(t, err)
+	assert.Equal(t, []SubscriptionCount{{Group: alice.String(), Count: 1}}, counts)
+
+	counts, err = repo.CountSubscriptions(ctx, CostFilter{Start: "01-2020", End: "12-2020"})
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+}
+
+func testRepositoryBillingPeriods(t *testing.T, repo SubscriptionRepository) {
+	ctx := context.Background()
+	userID := uuid.New()
+	end := func(s string) *string { return &s }
+
+	// Раз в год в марте
+	yearly := &models.Subscription{ServiceName: "Yandex Plus", Price: 1200, UserID: userID, StartDate: "03-2024",
+		BillingPeriod: models.BillingYearly}
+	_, err := repo.Create(ctx, yearly)
+	require.NoError(t, err)
+	assert.Equal(t, 1, yearly.BillingInterval)
+	// В январе, апреле, июле и октябре
+	repo.Create(ctx, &models.Subscription{ServiceName: "Cloud", Price: 300, UserID: userID, StartDate: "01-2025", EndDate: end("12-2025"),
+		BillingPeriod: models.BillingQuarterly, BillingInterval: 1})
+	// Раз в две недели с 1 января: 1, 15 и 29 января, 12 и 26 февраля
+	repo.Create(ctx, &models.Subscription{ServiceName: "Gym", Price: 70, UserID: userID, StartDate: "01-2025", EndDate: end("02-2025"),
+		BillingPeriod: models.BillingWeekly, BillingInterval: 2})
+	// Раз в два месяца с февраля
+	repo.Create(ctx, &models.Subscription{ServiceName: "Magazine", Price: 100, UserID: userID, StartDate: "02-2025",
+		BillingInterval: 2})
+
+	got, err := repo.GetByID(ctx, 4)
+	require.NoError(t, err)
+	assert.Equal(t, models.BillingMonthly, got.BillingPeriod)
+	assert.Equal(t, 2, got.BillingInterval)
+
+	// Недельная цена в месяц: 70 / 14 дней × 30.4375 дня = 152.1875
+	charged, normalized := totalCost(t, repo, CostFilter{Start: "01-2025", End: "12-2025", UserIDs: []uuid.UUID{userID}})
+	assert.Equal(t, models.Amount(1200+4*300+5*70+6*100), charged)
+	assert.Equal(t, models.Amount(1200+1200+304+550), normalized)
+
+	charged, normalized = totalCost(t, repo, CostFilter{Start: "02-2025", End: "03-2025"})
+	assert.Equal(t, models.Amount(1200+2*70+100), charged)
+	assert.Equal(t, models.Amount(200+200+152+100), normalized)
+
+	stats, err := repo.GetServiceStats(ctx, "02-2025")
+	require.NoError(t, err)
+	assert.Equal(t, []ServiceStats{
+		{ServiceName: "Cloud", ActiveCount: 1, MonthlySpend: models.Money{Amount: 100, Currency: "RUB"}},
+		{ServiceName: "Gym", ActiveCount: 1, MonthlySpend: models.Money{Amount: 152, Currency: "RUB"}},
+		{ServiceName: "Magazine", ActiveCount: 1, MonthlySpend: models.Money{Amount: 50, Currency: "RUB"}},
+		{ServiceName: "Yandex Plus", ActiveCount: 1, MonthlySpend: models.Money{Amount: 100, Currency: "RUB"}},
+	}, stats)
+
+	// Переход на ежемесячную оплату
+	period, interval := models.BillingMonthly, 1
+	require.NoError(t, repo.Patch(ctx, 1, models.SubscriptionPatch{BillingPeriod: &period, BillingInterval: &interval}, 0))
+	charged, normalized = totalCost(t, repo, CostFilter{Start: "01-2025", End: "02-2025", ServiceNames: []string{"Yandex Plus"}})
+	assert.Equal(t, models.Amount(2*1200), charged)
+	assert.Equal(t, models.Amount(2*1200), normalized)
+
+	unknown := models.BillingPeriod("daily")
+	assert.ErrorIs(t, repo.Patch(ctx, 1, models.SubscriptionPatch{BillingPeriod: &unknown}, 0), ErrValidation)
+}
+
+func testRepositoryGetServiceStats(t *testing.T, repo SubscriptionRepository) {
+	ctx := context.Background()
+	end := "02-2025"
+
+	repo.Create(ctx, &models.Subscription{ServiceName: "Spotify", Price: 200, UserID: uuid.New(), StartDate: "01-2025"})
+	repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: 500, UserID: uuid.New(), StartDate: "01-2025"})
+	repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: 700, UserID: uuid.New(), StartDate: "03-2024", EndDate: &end})
+	repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: 8, Currency: "EUR", UserID: uuid.New(), StartDate: "02-2025"})
+	// Ещё не началась
+	repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: 999, UserID: uuid.New(), StartDate: "03-2025"})
+
+	stats, err := repo.GetServiceStats(ctx, "02-2025")
+	assert.NoError(t, err)
+	assert.Equal(t, []ServiceStats{
+		{ServiceName: "Netflix", ActiveCount: 1, MonthlySpend: models.Money{Amount: 8, Currency: "EUR"}},
+		{ServiceName: "Netflix", ActiveCount: 2, MonthlySpend: models.Money{Amount: 1200, Currency: "RUB"}},
+		{ServiceName: "Spotify", ActiveCount: 1, MonthlySpend: models.Money{Amount: 200, Currency: "RUB"}},
+	}, stats)
+
+	stats, err = repo.GetServiceStats(ctx, "01-2020")
+	assert.NoError(t, err)
+	assert.Empty(t, stats)
+}
+
+func testRepositoryGetAll(t *testing.T, repo SubscriptionRepository) {
+	ctx := context.Background()
+	userID := uuid.New()
+	end := "06-2025"
+
+	repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: 500, UserID: userID, StartDate: "01-2025", EndDate: &end})
+	repo.Create(ctx, &models.Subscription{ServiceName: "Netflix Kids", Price: 300, UserID: userID, StartDate: "02-2025"})
+	repo.Create(ctx, &models.Subscription{ServiceName: "Spotify", Price: 300, UserID: userID, StartDate: "03-2025"})
+	repo.Create(ctx, &models.Subscription{ServiceName: "Netflix", Price: 700, UserID: uuid.New(), StartDate: "04-2025"})
+
+	page, err := repo.GetAll(ctx, SubscriptionFilter{UserID: userID, ServiceNamePrefix: "Net"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, page.Total)
+	assert.Len(t, page.Items, 2)
+
+	page, err = repo.GetAll(ctx, SubscriptionFilter{ActiveIn: "07-2025"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, page.Total)
+
+	// Обход по курсору с сортировкой по цене по убыванию
+	filter := SubscriptionFilter{Sort: "price", Order: "desc", Limit: 1}
+	var ids []int
+	for {
+		page, err = repo.GetAll(ctx, filter)
+		assert.NoError(t, err)
+		for _, sub := range page.Items {
+			ids = append(ids, sub.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []int{4, 1, 3, 2}, ids)
+
+	// Бессрочные подписки при сортировке по end_date идут последними
+	page, err = repo.GetAll(ctx, SubscriptionFilter{Sort: "end_date", Limit: 2, Offset: 0})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, []int{page.Items[0].ID, page.Items[1].ID})
+
+	page, err = repo.GetAll(ctx, SubscriptionFilter{Offset: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items)
+
+	_, err = repo.GetAll(ctx, SubscriptionFilter{Sort: "password"})
+	assert.ErrorIs(t, err, ErrValidation)
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"rest-service/internal/logging"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("rest-service/internal/repository")
+
+// rowsAffectedKey — число строк, изменённых запросом UPDATE или DELETE
+const rowsAffectedKey = attribute.Key("db.response.affected_rows")
+
+// querySpan — спан вызова метода репозитория с SQL-запросом.
+// При завершении запрос также пишется в лог запроса на уровне debug
+type querySpan struct {
+	trace.Span
+	ctx    context.Context
+	name   string
+	start  time.Time
+	fields log.Fields
+}
+
+// startQuerySpan начинает спан для запроса query к таблице subscriptions.
+// Значения параметров запроса в спан не попадают
+func startQuerySpan(ctx context.Context, name, operation, query string) (context.Context, *querySpan) {
+	return startTableSpan(ctx, "subscriptions", name, operation, query)
+}
+
+// startTableSpan начинает спан для запроса query к таблице table
+func startTableSpan(ctx context.Context, table, name, operation, query string) (context.Context, *querySpan) {
+	ctx, span := tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBCollectionName(table),
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(query),
+		))
+	return ctx, &querySpan{Span: span, ctx: ctx, name: name, start: time.Now(), fields: log.Fields{}}
+}
+
+func (s *querySpan) returnedRows(n int) {
+	s.SetAttributes(semconv.DBResponseReturnedRows(n))
+	s.fields["rows"] = n
+}
+
+func (s *querySpan) affectedRows(n int64) {
+	s.SetAttributes(rowsAffectedKey.Int64(n))
+	s.fields["rows"] = n
+}
+
+// end записывает ошибку и завершает спан. Отсутствие записи — штатный результат и ошибкой спана не считается
+func (s *querySpan) end(err error) {
+	entry := logging.FromContext(s.ctx).WithFields(s.fields).WithFields(log.Fields{
+		"query":       s.name,
+		"duration_ms": float64(time.Since(s.start).Microseconds()) / 1000,
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		s.RecordError(err)
+		s.SetStatus(codes.Error, err.Error())
+		entry = entry.WithError(err)
+	}
+	entry.Debug("SQL query executed")
+	s.End()
+}
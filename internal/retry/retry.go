@@ -0,0 +1,52 @@
+// Package retry повторяет операции с экспоненциальной задержкой
+package retry
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff — параметры задержки между попытками
+type Backoff struct {
+	Initial    time.Duration // задержка перед второй попыткой
+	Max        time.Duration // верхняя граница задержки
+	Multiplier float64       // во сколько раз растёт задержка после каждой неудачи
+}
+
+// DefaultBackoff подходит для ожидания запуска зависимостей: 0.5s, 1s, 2s, ... до 10s
+var DefaultBackoff = Backoff{Initial: 500 * time.Millisecond, Max: 10 * time.Second, Multiplier: 2}
+
+// Do вызывает fn, пока она не выполнится успешно или не будет отменён ctx.
+// Перед каждой повторной попыткой вызывается onRetry (если задан).
+// При отмене ctx возвращается последняя ошибка fn
+func Do(ctx context.Context, b Backoff, fn func(ctx context.Context) error, onRetry func(attempt int, delay time.Duration, err error)) error {
+	delay := b.Initial
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		// Случайный разброс ±20%, чтобы несколько реплик не стучались в БД одновременно
+		wait := time.Duration(float64(delay) * (0.8 + 0.4*rand.Float64()))
+		if onRetry != nil {
+			onRetry(attempt, wait, err)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		delay = time.Duration(float64(delay) * b.Multiplier)
+		if delay > b.Max {
+			delay = b.Max
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var fastBackoff = Backoff{Initial: time.Millisecond, Max: 4 * time.Millisecond, Multiplier: 2}
+
+func TestDo_SucceedsAfterRetries(t *testing.T) {
+	calls := 0
+	var delays []time.Duration
+	err := Do(context.Background(), fastBackoff, func(ctx context.Context) error {
+		calls++
+		if calls < 5 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, func(attempt int, delay time.Duration, err error) {
+		delays = append(delays, delay)
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, calls)
+	assert.Len(t, delays, 4)
+	for _, d := range delays {
+		assert.LessOrEqual(t, d, time.Duration(float64(fastBackoff.Max)*1.2))
+	}
+}
+
+func TestDo_StopsOnContextDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	lastErr := errors.New("connection refused")
+	err := Do(ctx, fastBackoff, func(ctx context.Context) error { return lastErr }, nil)
+	assert.ErrorIs(t, err, lastErr)
+}
@@ -0,0 +1,133 @@
+// Package server управляет жизненным циклом HTTP-сервера: таймауты,
+// корректная остановка по сигналу и хуки завершения
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"rest-service/internal/config"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Hook — действие, выполняемое при остановке сервера после завершения всех запросов
+type Hook func(ctx context.Context) error
+
+type namedHook struct {
+	name string
+	fn   Hook
+}
+
+// Server — HTTP-сервер с корректной остановкой
+type Server struct {
+	srv *http.Server
+	cfg config.ServerConfig
+
+	mu          sync.Mutex
+	hooks       []namedHook
+	beforeHooks []func()
+}
+
+func New(handler http.Handler, cfg config.ServerConfig) *Server {
+	return &Server{
+		cfg: cfg,
+		srv: &http.Server{
+			Addr:              cfg.Addr(),
+			Handler:           handler,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			ReadTimeout:       cfg.ReadTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		},
+	}
+}
+
+// OnShutdown регистрирует хук остановки. Хуки выполняются в обратном порядке регистрации,
+// как defer: ресурсы, открытые раньше, закрываются позже
+func (s *Server) OnShutdown(name string, fn Hook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, namedHook{name: name, fn: fn})
+}
+
+// BeforeShutdown регистрирует функцию, которая вызывается сразу после запроса на остановку,
+// до паузы ShutdownDrainDelay и ожидания текущих запросов. Например, чтобы проба готовности перестала отвечать 200
+func (s *Server) BeforeShutdown(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.beforeHooks = append(s.beforeHooks, fn)
+}
+
+// Run слушает адрес из конфигурации и обслуживает запросы, пока не будет отменён ctx
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve обслуживает запросы на ln, пока не будет отменён ctx. После отмены сервер ещё ShutdownDrainDelay
+// принимает запросы, чтобы балансировщик успел увидеть неготовность, затем перестаёт принимать соединения,
+// ждёт завершения текущих запросов не дольше ShutdownTimeout и выполняет хуки остановки
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Infof("Server is listening on %s", ln.Addr())
+		serveErr <- s.srv.Serve(ln)
+	}()
+
+	var err error
+	drain := false
+	select {
+	case err = <-serveErr:
+		// Сервер упал сам, без запроса на остановку: ждать балансировщик незачем
+	case <-ctx.Done():
+		drain = s.cfg.ShutdownDrainDelay > 0
+	}
+
+	s.mu.Lock()
+	beforeHooks := s.beforeHooks
+	s.mu.Unlock()
+	for _, fn := range beforeHooks {
+		fn()
+	}
+
+	if drain {
+		log.WithField("drain_delay", s.cfg.ShutdownDrainDelay.String()).Info("Shutting down server, draining traffic")
+		select {
+		case err = <-serveErr:
+		case <-time.After(s.cfg.ShutdownDrainDelay):
+		}
+	}
+	log.Info("Shutting down server, waiting for in-flight requests")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	if shutdownErr := s.srv.Shutdown(shutdownCtx); shutdownErr != nil {
+		log.WithError(shutdownErr).Warn("In-flight requests did not finish before shutdown timeout")
+		s.srv.Close()
+		err = errors.Join(err, shutdownErr)
+	}
+	if err != nil && errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+
+	s.mu.Lock()
+	hooks := s.hooks
+	s.mu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		if hookErr := h.fn(shutdownCtx); hookErr != nil {
+			log.WithError(hookErr).Errorf("Shutdown hook %q failed", h.name)
+			err = errors.Join(err, hookErr)
+		}
+	}
+	log.Info("Server stopped")
+	return err
+}
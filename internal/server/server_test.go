@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"rest-service/internal/config"
+	"rest-service/internal/health"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig(shutdownTimeout time.Duration) config.ServerConfig {
+	cfg := config.Default().Server
+	cfg.ShutdownTimeout = shutdownTimeout
+	cfg.ShutdownDrainDelay = 0
+	return cfg
+}
+
+// startServer запускает сервер на свободном порту и возвращает его адрес и канал с результатом Serve
+func startServer(t *testing.T, ctx context.Context, s *Server) (string, <-chan error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, ln) }()
+	return "http://" + ln.Addr().String(), done
+}
+
+func TestServer_WaitsForInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+
+	var order []string
+	s := New(handler, testConfig(5*time.Second))
+	s.BeforeShutdown(func() { order = append(order, "before") })
+	s.OnShutdown("first", func(ctx context.Context) error { order = append(order, "first"); return nil })
+	s.OnShutdown("second", func(ctx context.Context) error { order = append(order, "second"); return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, done := startServer(t, ctx, s)
+
+	respCh := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get(addr)
+		if err == nil {
+			respCh <- resp
+		}
+		close(respCh)
+	}()
+	<-started
+	cancel()
+
+	// Пока запрос обрабатывается, сервер не завершается и хуки не вызываются
+	select {
+	case <-done:
+		t.Fatal("server stopped before in-flight request finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Equal(t, []string{"before"}, order)
+
+	close(release)
+	resp := <-respCh
+	require.NotNil(t, resp)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "done", string(body))
+
+	require.NoError(t, <-done)
+	assert.Equal(t, []string{"before", "second", "first"}, order)
+}
+
+func TestServer_ShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+
+	hookCalled := false
+	s := New(handler, testConfig(50*time.Millisecond))
+	s.OnShutdown("db", func(ctx context.Context) error { hookCalled = true; return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, done := startServer(t, ctx, s)
+	go http.Get(addr)
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after shutdown timeout")
+	}
+	assert.True(t, hookCalled, "shutdown hooks must run even when requests are cut off")
+}
+
+func TestServer_DrainDelay(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !checker.Readiness(r.Context()).Ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	cfg := testConfig(time.Second)
+	cfg.ShutdownDrainDelay = 300 * time.Millisecond
+	s := New(handler, cfg)
+	s.BeforeShutdown(checker.SetShuttingDown)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, done := startServer(t, ctx, s)
+	resp, err := http.Get(addr + "/readyz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cancel()
+	// Пока идёт пауза, сервер ещё принимает запросы, но проба готовности уже отвечает 503
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(addr + "/readyz")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusServiceUnavailable
+	}, 200*time.Millisecond, 10*time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("server stopped before the drain delay passed")
+	default:
+	}
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after the drain delay")
+	}
+}
@@ -0,0 +1,188 @@
+// Package validation содержит правила проверки входных данных API и
+// преобразование ошибок валидатора в список ошибок по полям
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"rest-service/internal/models"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// MinPrice и MaxPrice — допустимые границы стоимости одного списания по подписке в единицах валюты
+	MinPrice = 0
+	MaxPrice = 1_000_000
+
+	// MaxServiceNameLength — максимальная длина названия сервиса в символах (VARCHAR(255) в БД)
+	MaxServiceNameLength = 255
+
+	// MaxBillingInterval — наибольшее число периодов между списаниями (например, раз в 52 недели)
+	MaxBillingInterval = 52
+)
+
+// serviceNameRe — буквы любых алфавитов, цифры, пробелы и типичная для названий пунктуация
+var serviceNameRe = regexp.MustCompile(`^[\p{L}\p{N} .,:;&'"!?+()/_#@-]+$`)
+
+var once sync.Once
+
+// FieldError — ошибка валидации одного поля
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors — набор ошибок валидации по полям
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + ": " + fe.Message
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// Register регистрирует пользовательские правила в валидаторе gin.
+// Повторные вызовы безопасны
+func Register() {
+	once.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			panic("validation: unexpected gin validator engine")
+		}
+		// В ошибках используем имена полей из JSON, а не из Go-структуры
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+		mustRegister(v.RegisterValidation("month", validMonth))
+		mustRegister(v.RegisterValidation("price", validPrice))
+		mustRegister(v.RegisterValidation("service_name", validServiceName))
+		mustRegister(v.RegisterValidation("billing_period", validBillingPeriod))
+		mustRegister(v.RegisterValidation("billing_interval", validBillingInterval))
+		v.RegisterStructValidation(subscriptionStructLevel, models.Subscription{})
+	})
+}
+
+func mustRegister(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Struct проверяет структуру теми же правилами, что применяются при биндинге запроса
+func Struct(obj interface{}) error {
+	Register()
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// Currency проверяет, что code — код валюты по ISO 4217
+func Currency(code string) bool {
+	Register()
+	return binding.Validator.Engine().(*validator.Validate).Var(code, "iso4217") == nil
+}
+
+// FieldErrors преобразует ошибку валидатора в список ошибок по полям.
+// Если err не является ошибкой валидации, возвращает nil
+func FieldErrors(err error) Errors {
+	var fieldErrs Errors
+	if errors.As(err, &fieldErrs) {
+		return fieldErrs
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil
+	}
+	result := make(Errors, 0, len(verrs))
+	for _, fe := range verrs {
+		result = append(result, FieldError{Field: fe.Field(), Message: message(fe)})
+	}
+	return result
+}
+
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "month":
+		return "must be a month in MM-YYYY format"
+	case "price":
+		return fmt.Sprintf("must be between %d and %d", MinPrice, MaxPrice)
+	case "service_name":
+		return fmt.Sprintf("must be 1-%d characters of letters, digits, spaces and punctuation without leading or trailing spaces", MaxServiceNameLength)
+	case "billing_period":
+		return "must be one of: weekly monthly quarterly yearly"
+	case "billing_interval":
+		return fmt.Sprintf("must be between 1 and %d", MaxBillingInterval)
+	case "iso4217":
+		return "must be an ISO 4217 currency code, e.g. RUB"
+	case "oneof":
+		return "must be one of: " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param() + " characters long"
+	case "gtefield":
+		return "must not be before " + fe.Param()
+	default:
+		return "failed on " + fe.Tag() + " rule"
+	}
+}
+
+func validMonth(fl validator.FieldLevel) bool {
+	_, err := models.ParseMonth(fl.Field().String())
+	return err == nil
+}
+
+func validPrice(fl validator.FieldLevel) bool {
+	price := models.Amount(fl.Field().Int())
+	return price >= models.Units(MinPrice) && price <= models.Units(MaxPrice)
+}
+
+func validServiceName(fl validator.FieldLevel) bool {
+	name := fl.Field().String()
+	return utf8.RuneCountInString(name) <= MaxServiceNameLength &&
+		strings.TrimSpace(name) == name &&
+		serviceNameRe.MatchString(name)
+}
+
+func validBillingPeriod(fl validator.FieldLevel) bool {
+	switch models.BillingPeriod(fl.Field().String()) {
+	case models.BillingWeekly, models.BillingMonthly, models.BillingQuarterly, models.BillingYearly:
+		return true
+	}
+	return false
+}
+
+func validBillingInterval(fl validator.FieldLevel) bool {
+	n := fl.Field().Int()
+	return n >= 1 && n <= MaxBillingInterval
+}
+
+// subscriptionStructLevel проверяет, что дата окончания подписки не раньше даты начала
+func subscriptionStructLevel(sl validator.StructLevel) {
+	sub := sl.Current().Interface().(models.Subscription)
+	if sub.EndDate == nil {
+		return
+	}
+	start, err := models.ParseMonth(sub.StartDate)
+	if err != nil {
+		return
+	}
+	end, err := models.ParseMonth(*sub.EndDate)
+	if err != nil {
+		return
+	}
+	if end.Before(start) {
+		sl.ReportError(sub.EndDate, "end_date", "EndDate", "gtefield", "start_date")
+	}
+}
@@ -0,0 +1,69 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"rest-service/internal/models"
+)
+
+func TestStruct_Subscription(t *testing.T) {
+	end := func(s string) *string { return &s }
+	valid := models.Subscription{ServiceName: "Yandex Plus", Price: 400, UserID: uuid.New(), StartDate: "07-2025"}
+
+	tests := []struct {
+		name   string
+		modify func(s *models.Subscription)
+		field  string
+	}{
+		{"valid", func(s *models.Subscription) {}, ""},
+		{"valid with end date", func(s *models.Subscription) { s.EndDate = end("07-2025") }, ""},
+		{"cyrillic name", func(s *models.Subscription) { s.ServiceName = "Кинопоиск HD" }, ""},
+		{"empty name", func(s *models.Subscription) { s.ServiceName = "" }, "service_name"},
+		{"padded name", func(s *models.Subscription) { s.ServiceName = " Netflix" }, "service_name"},
+		{"control chars in name", func(s *models.Subscription) { s.ServiceName = "Net\nflix" }, "service_name"},
+		{"long name", func(s *models.Subscription) { s.ServiceName = strings.Repeat("a", MaxServiceNameLength+1) }, "service_name"},
+		{"negative price", func(s *models.Subscription) { s.Price = -1 }, "price"},
+		{"huge price", func(s *models.Subscription) { s.Price = models.Units(MaxPrice) + 1 }, "price"},
+		{"nil user", func(s *models.Subscription) { s.UserID = uuid.Nil }, "user_id"},
+		{"missing start", func(s *models.Subscription) { s.StartDate = "" }, "start_date"},
+		{"bad start", func(s *models.Subscription) { s.StartDate = "2025-07" }, "start_date"},
+		{"bad month", func(s *models.Subscription) { s.EndDate = end("13-2025") }, "end_date"},
+		{"end before start", func(s *models.Subscription) { s.EndDate = end("06-2025") }, "end_date"},
+		{"yearly billing", func(s *models.Subscription) { s.BillingPeriod, s.BillingInterval = models.BillingYearly, 1 }, ""},
+		{"every two weeks", func(s *models.Subscription) { s.BillingPeriod, s.BillingInterval = models.BillingWeekly, 2 }, ""},
+		{"unknown billing period", func(s *models.Subscription) { s.BillingPeriod = "daily" }, "billing_period"},
+		{"negative billing interval", func(s *models.Subscription) { s.BillingInterval = -1 }, "billing_interval"},
+		{"dollars", func(s *models.Subscription) { s.Currency = "USD" }, ""},
+		{"unknown currency", func(s *models.Subscription) { s.Currency = "XYZ" }, "currency"},
+		{"lowercase currency", func(s *models.Subscription) { s.Currency = "usd" }, "currency"},
+		{"huge billing interval", func(s *models.Subscription) { s.BillingInterval = MaxBillingInterval + 1 }, "billing_interval"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := valid
+			tt.modify(&sub)
+			err := Struct(sub)
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+			fields := FieldErrors(err)
+			if assert.Len(t, fields, 1) {
+				assert.Equal(t, tt.field, fields[0].Field)
+			}
+		})
+	}
+}
+
+func TestCurrency(t *testing.T) {
+	assert.True(t, Currency("RUB"))
+	assert.True(t, Currency("USD"))
+	assert.False(t, Currency("usd"))
+	assert.False(t, Currency("XYZ"))
+	assert.False(t, Currency(""))
+}
@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Даты хранились строками MM-YYYY, из-за чего сравнения шли лексикографически.
+-- Переводим их в DATE (первое число месяца), сохраняя существующие данные
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_start_date_check;
+
+ALTER TABLE subscriptions
+    ALTER COLUMN start_date TYPE DATE USING TO_DATE(start_date, 'MM-YYYY'),
+    ALTER COLUMN end_date TYPE DATE USING TO_DATE(NULLIF(end_date, ''), 'MM-YYYY');
+
+-- Раньше порядок дат не проверялся. Подписки с end_date раньше start_date не исправляются молча:
+-- миграция откатывается и перечисляет их, чтобы даты поправили вручную
+DO $$
+DECLARE
+    invalid TEXT;
+BEGIN
+    SELECT string_agg(id || ' (' || TO_CHAR(start_date, 'MM-YYYY') || ' - ' || TO_CHAR(end_date, 'MM-YYYY') || ')', ', ' ORDER BY id)
+    INTO invalid
+    FROM subscriptions
+    WHERE end_date < start_date;
+
+    IF invalid IS NOT NULL THEN
+        RAISE EXCEPTION 'subscriptions with end_date before start_date: %', invalid
+            USING HINT = 'fix end_date of these subscriptions and run the migration again';
+    END IF;
+END $$;
+
+ALTER TABLE subscriptions
+    ADD CONSTRAINT subscriptions_end_date_check CHECK (end_date IS NULL OR end_date >= start_date);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_end_date_check;
+
+ALTER TABLE subscriptions
+    ALTER COLUMN start_date TYPE VARCHAR(7) USING TO_CHAR(start_date, 'MM-YYYY'),
+    ALTER COLUMN end_date TYPE VARCHAR(7) USING TO_CHAR(end_date, 'MM-YYYY');
+
+ALTER TABLE subscriptions
+    ADD CONSTRAINT subscriptions_start_date_check CHECK (start_date ~ '^\d{2}-\d{4}$');
+-- +goose StatementEnd
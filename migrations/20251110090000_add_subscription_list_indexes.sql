@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Индексы под фильтры и сортировку GET /subscriptions.
+-- varchar_pattern_ops позволяет использовать индекс и для точного совпадения, и для поиска по префиксу (LIKE 'abc%')
+CREATE INDEX subscriptions_user_id_idx ON subscriptions (user_id, id);
+CREATE INDEX subscriptions_service_name_idx ON subscriptions (service_name varchar_pattern_ops);
+CREATE INDEX subscriptions_start_date_idx ON subscriptions (start_date, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS subscriptions_start_date_idx;
+DROP INDEX IF EXISTS subscriptions_service_name_idx;
+DROP INDEX IF EXISTS subscriptions_user_id_idx;
+-- +goose StatementEnd
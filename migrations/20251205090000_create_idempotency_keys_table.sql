@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Ответы на запросы с заголовком Idempotency-Key. status = 0 — запрос ещё выполняется
+CREATE TABLE idempotency_keys (
+    key VARCHAR(512) PRIMARY KEY,
+    request_hash CHAR(64) NOT NULL,
+    status INTEGER NOT NULL DEFAULT 0,
+    response BYTEA,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE idempotency_keys;
+-- +goose StatementEnd
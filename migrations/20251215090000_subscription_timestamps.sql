@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+-- created_at и updated_at отдаются в API: время хранится с часовым поясом и всегда заполнено.
+-- Значения без пояса были записаны в поясе сервера БД, поэтому переводятся в нём же
+UPDATE subscriptions SET created_at = COALESCE(created_at, CURRENT_TIMESTAMP), updated_at = COALESCE(updated_at, created_at, CURRENT_TIMESTAMP);
+
+ALTER TABLE subscriptions
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at::TIMESTAMPTZ,
+    ALTER COLUMN created_at SET DEFAULT NOW(),
+    ALTER COLUMN created_at SET NOT NULL,
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at::TIMESTAMPTZ,
+    ALTER COLUMN updated_at SET DEFAULT NOW(),
+    ALTER COLUMN updated_at SET NOT NULL;
+
+-- Индексы под фильтры created_since и updated_since
+CREATE INDEX subscriptions_created_at_idx ON subscriptions (created_at, id);
+CREATE INDEX subscriptions_updated_at_idx ON subscriptions (updated_at, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS subscriptions_updated_at_idx;
+DROP INDEX IF EXISTS subscriptions_created_at_idx;
+
+ALTER TABLE subscriptions
+    ALTER COLUMN created_at DROP NOT NULL,
+    ALTER COLUMN created_at TYPE TIMESTAMP,
+    ALTER COLUMN created_at SET DEFAULT CURRENT_TIMESTAMP,
+    ALTER COLUMN updated_at DROP NOT NULL,
+    ALTER COLUMN updated_at TYPE TIMESTAMP,
+    ALTER COLUMN updated_at SET DEFAULT CURRENT_TIMESTAMP;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- price — стоимость одного списания, которое происходит раз в billing_interval периодов billing_period.
+-- Существующие подписки оплачивались помесячно
+ALTER TABLE subscriptions
+    ADD COLUMN billing_period VARCHAR(16) NOT NULL DEFAULT 'monthly'
+        CONSTRAINT subscriptions_billing_period_check CHECK (billing_period IN ('weekly', 'monthly', 'quarterly', 'yearly')),
+    ADD COLUMN billing_interval INTEGER NOT NULL DEFAULT 1
+        CONSTRAINT subscriptions_billing_interval_check CHECK (billing_interval > 0);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE subscriptions
+    DROP COLUMN billing_interval,
+    DROP COLUMN billing_period;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Цена подписки задаётся в её валюте (ISO 4217), существующие подписки — в рублях
+ALTER TABLE subscriptions ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'RUB';
+
+-- Курс — цена одной единицы валюты в рублях, действующая с effective_date до следующего курса той же валюты
+CREATE TABLE exchange_rates (
+    currency CHAR(3) NOT NULL,
+    effective_date DATE NOT NULL,
+    rate NUMERIC(20, 10) NOT NULL CHECK (rate > 0),
+    PRIMARY KEY (currency, effective_date)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE exchange_rates;
+ALTER TABLE subscriptions DROP COLUMN currency;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Цена хранится в сотых долях единицы валюты (копейках, центах), чтобы не терять дробную часть
+ALTER TABLE subscriptions ALTER COLUMN price TYPE BIGINT USING price::BIGINT * 100;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE subscriptions ALTER COLUMN price TYPE INTEGER USING ROUND(price / 100.0)::INTEGER;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Случайный идентификатор резервирования ключа: завершить или освободить запись может только тот запрос,
+-- который её занял, даже если блокировка истекла и ключ занял повторный запрос
+ALTER TABLE idempotency_keys ADD COLUMN token VARCHAR(64) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE idempotency_keys DROP COLUMN token;
+-- +goose StatementEnd
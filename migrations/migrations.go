@@ -0,0 +1,69 @@
+// Package migrations встраивает SQL-миграции в бинарник и применяет их через goose
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/pressly/goose/v3"
+)
+
+//go:embed *.sql
+var postgresFS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFS embed.FS
+
+// Up применяет все недостающие миграции для указанного драйвера БД (postgres или sqlite).
+// Если dir не пуст, миграции читаются из этого каталога вместо встроенных в бинарник
+func Up(ctx context.Context, db *sql.DB, driver, dir string) error {
+	provider, err := newProvider(db, driver, dir)
+	if err != nil {
+		return err
+	}
+	_, err = provider.Up(ctx)
+	return err
+}
+
+// CheckVersion проверяет, что в БД применены все миграции, известные сервису
+func CheckVersion(ctx context.Context, db *sql.DB, driver, dir string) error {
+	provider, err := newProvider(db, driver, dir)
+	if err != nil {
+		return err
+	}
+	current, target, err := provider.GetVersions(ctx)
+	if err != nil {
+		return err
+	}
+	if current != target {
+		return fmt.Errorf("schema version %d, expected %d", current, target)
+	}
+	return nil
+}
+
+func newProvider(db *sql.DB, driver, dir string) (*goose.Provider, error) {
+	dialect := goose.DialectPostgres
+	if driver == "sqlite" {
+		dialect = goose.DialectSQLite3
+	}
+	if dir != "" {
+		return goose.NewProvider(dialect, db, os.DirFS(dir))
+	}
+
+	switch driver {
+	case "postgres":
+		return goose.NewProvider(goose.DialectPostgres, db, postgresFS)
+	case "sqlite":
+		fsys, err := fs.Sub(sqliteFS, "sqlite")
+		if err != nil {
+			return nil, err
+		}
+		return goose.NewProvider(goose.DialectSQLite3, db, fsys)
+	default:
+		return nil, fmt.Errorf("no migrations for driver %q", driver)
+	}
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestCheckVersion(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	assert.ErrorContains(t, CheckVersion(ctx, db, "sqlite", ""), "schema version 0")
+
+	require.NoError(t, Up(ctx, db, "sqlite", ""))
+	assert.NoError(t, CheckVersion(ctx, db, "sqlite", ""))
+}
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Даты хранятся как текст ISO 8601 (YYYY-MM-01), поэтому сравниваются и сортируются корректно
+CREATE TABLE subscriptions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    service_name TEXT NOT NULL CHECK (length(service_name) <= 255),
+    price INTEGER NOT NULL,
+    user_id TEXT NOT NULL,
+    start_date TEXT NOT NULL CHECK (start_date GLOB '[0-9][0-9][0-9][0-9]-[0-1][0-9]-01'),
+    end_date TEXT CHECK (end_date GLOB '[0-9][0-9][0-9][0-9]-[0-1][0-9]-01'),
+    created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+    updated_at TEXT DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT subscriptions_end_date_check CHECK (end_date IS NULL OR end_date >= start_date)
+);
+
+CREATE INDEX subscriptions_user_id_idx ON subscriptions (user_id, id);
+CREATE INDEX subscriptions_service_name_idx ON subscriptions (service_name);
+CREATE INDEX subscriptions_start_date_idx ON subscriptions (start_date, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE subscriptions;
+-- +goose StatementEnd
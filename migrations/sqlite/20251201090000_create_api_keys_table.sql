@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Ключи доступа для межсервисных вызовов. Хранится только SHA-256 ключа.
+-- Скоупы перечисляются через пробел, время хранится как текст RFC 3339 в UTC
+CREATE TABLE api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL CHECK (length(name) <= 255),
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    expires_at TEXT,
+    last_used_at TEXT,
+    created_at TEXT NOT NULL,
+    revoked_at TEXT
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE api_keys;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Время хранится текстом RFC 3339 в UTC фиксированной ширины, чтобы сравниваться как строки.
+-- Новые строки получают его из приложения, а значения по умолчанию CURRENT_TIMESTAMP приводятся к тому же виду
+UPDATE subscriptions SET
+    created_at = strftime('%Y-%m-%dT%H:%M:%S.000000000Z', COALESCE(created_at, CURRENT_TIMESTAMP)),
+    updated_at = strftime('%Y-%m-%dT%H:%M:%S.000000000Z', COALESCE(updated_at, created_at, CURRENT_TIMESTAMP));
+
+CREATE INDEX subscriptions_created_at_idx ON subscriptions (created_at, id);
+CREATE INDEX subscriptions_updated_at_idx ON subscriptions (updated_at, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS subscriptions_updated_at_idx;
+DROP INDEX IF EXISTS subscriptions_created_at_idx;
+-- +goose StatementEnd
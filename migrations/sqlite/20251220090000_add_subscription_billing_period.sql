@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- price — стоимость одного списания, которое происходит раз в billing_interval периодов billing_period.
+-- Существующие подписки оплачивались помесячно
+ALTER TABLE subscriptions ADD COLUMN billing_period TEXT NOT NULL DEFAULT 'monthly'
+    CHECK (billing_period IN ('weekly', 'monthly', 'quarterly', 'yearly'));
+ALTER TABLE subscriptions ADD COLUMN billing_interval INTEGER NOT NULL DEFAULT 1 CHECK (billing_interval > 0);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE subscriptions DROP COLUMN billing_interval;
+ALTER TABLE subscriptions DROP COLUMN billing_period;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Цена подписки задаётся в её валюте (ISO 4217), существующие подписки — в рублях
+ALTER TABLE subscriptions ADD COLUMN currency TEXT NOT NULL DEFAULT 'RUB' CHECK (length(currency) = 3);
+
+-- Курс — цена одной единицы валюты в рублях, действующая с effective_date (YYYY-MM-DD) до следующего курса той же валюты
+CREATE TABLE exchange_rates (
+    currency TEXT NOT NULL,
+    effective_date TEXT NOT NULL CHECK (effective_date GLOB '[0-9][0-9][0-9][0-9]-[0-1][0-9]-[0-3][0-9]'),
+    rate REAL NOT NULL CHECK (rate > 0),
+    PRIMARY KEY (currency, effective_date)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE exchange_rates;
+ALTER TABLE subscriptions DROP COLUMN currency;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Цена хранится в сотых долях единицы валюты (копейках, центах), чтобы не терять дробную часть.
+-- INTEGER в SQLite уже 64-битный, меняются только значения
+UPDATE subscriptions SET price = price * 100;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE subscriptions SET price = CAST(ROUND(price / 100.0) AS INTEGER);
+-- +goose StatementEnd